@@ -0,0 +1,220 @@
+// Package accounts はログインユーザーとセッションを扱います。
+// サーバーと tools/user コマンドの両方から使います。
+package accounts
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// セッションの有効期間
+const SessionTTL = 30 * 24 * time.Hour
+
+// パスワードの最低文字数
+const MinPasswordLength = 8
+
+var (
+	ErrInvalidCredentials = errors.New("ユーザー名またはパスワードが違います")
+	ErrUserNotFound       = errors.New("ユーザーが見つかりません")
+	ErrUserExists         = errors.New("ユーザーは既に存在します")
+)
+
+type User struct {
+	ID       int    `json:"id"`
+	Username string `json:"username"`
+	IsAdmin  bool   `json:"is_admin"`
+}
+
+func HashPassword(password string) (string, error) {
+	if len([]rune(password)) < MinPasswordLength {
+		return "", fmt.Errorf("パスワードは%d文字以上にしてください", MinPasswordLength)
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// CreateUser はユーザーを追加します。
+func CreateUser(db *sql.DB, username, password string, isAdmin bool) (*User, error) {
+	username = strings.TrimSpace(username)
+	if username == "" {
+		return nil, errors.New("ユーザー名は必須です")
+	}
+	hash, err := HashPassword(password)
+	if err != nil {
+		return nil, err
+	}
+
+	var existing int
+	if err := db.QueryRow("SELECT id FROM users WHERE username = ?", username).Scan(&existing); err == nil {
+		return nil, ErrUserExists
+	}
+
+	res, err := db.Exec("INSERT INTO users(username, password_hash, is_admin) VALUES(?, ?, ?)", username, hash, isAdmin)
+	if err != nil {
+		return nil, err
+	}
+	id, _ := res.LastInsertId()
+	return &User{ID: int(id), Username: username, IsAdmin: isAdmin}, nil
+}
+
+// SetPassword はパスワードを変更し、そのユーザーの既存セッションを全て無効にします。
+func SetPassword(db *sql.DB, username, password string) error {
+	hash, err := HashPassword(password)
+	if err != nil {
+		return err
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	res, err := tx.Exec("UPDATE users SET password_hash = ?, updated_at = datetime('now','localtime') WHERE username = ?", hash, username)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		tx.Rollback()
+		return ErrUserNotFound
+	}
+	if _, err := tx.Exec("DELETE FROM sessions WHERE user_id = (SELECT id FROM users WHERE username = ?)", username); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// DeleteUser はユーザーとそのセッションを削除します。
+func DeleteUser(db *sql.DB, username string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM sessions WHERE user_id = (SELECT id FROM users WHERE username = ?)", username); err != nil {
+		tx.Rollback()
+		return err
+	}
+	// 変更履歴は actor に名前が残っているので、ユーザーへの参照だけ外す
+	if _, err := tx.Exec("UPDATE audit_log SET user_id = NULL WHERE user_id = (SELECT id FROM users WHERE username = ?)", username); err != nil {
+		tx.Rollback()
+		return err
+	}
+	res, err := tx.Exec("DELETE FROM users WHERE username = ?", username)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		tx.Rollback()
+		return ErrUserNotFound
+	}
+	return tx.Commit()
+}
+
+func ListUsers(db *sql.DB) ([]User, error) {
+	rows, err := db.Query("SELECT id, username, is_admin FROM users ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := []User{}
+	for rows.Next() {
+		var u User
+		if err := rows.Scan(&u.ID, &u.Username, &u.IsAdmin); err != nil {
+			return nil, err
+		}
+		users = append(users, u)
+	}
+	return users, rows.Err()
+}
+
+func CountUsers(db *sql.DB) (int, error) {
+	var n int
+	err := db.QueryRow("SELECT COUNT(*) FROM users").Scan(&n)
+	return n, err
+}
+
+// Authenticate はユーザー名とパスワードを照合します。
+func Authenticate(db *sql.DB, username, password string) (*User, error) {
+	var u User
+	var hash string
+	err := db.QueryRow("SELECT id, username, is_admin, password_hash FROM users WHERE username = ?", username).
+		Scan(&u.ID, &u.Username, &u.IsAdmin, &hash)
+	if err == sql.ErrNoRows {
+		// ユーザーの有無で応答時間が変わらないようにダミーの照合をしておく
+		bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+		return nil, ErrInvalidCredentials
+	} else if err != nil {
+		return nil, err
+	}
+	if bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) != nil {
+		return nil, ErrInvalidCredentials
+	}
+	return &u, nil
+}
+
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("kimichan-dummy-password"), bcrypt.DefaultCost)
+
+// CreateSession は新しいセッションを発行し、クッキーに入れるトークンを返します。
+// DB にはトークンのハッシュだけを保存します。
+func CreateSession(db *sql.DB, userID int) (string, time.Time, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", time.Time{}, err
+	}
+	token := hex.EncodeToString(buf)
+	expires := time.Now().Add(SessionTTL)
+
+	_, err := db.Exec("INSERT INTO sessions(token_hash, user_id, expires_at) VALUES(?, ?, ?)",
+		hashToken(token), userID, expires.UTC().Format(time.RFC3339))
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return token, expires, nil
+}
+
+// LookupSession は有効なセッションのユーザーを返します。期限切れ・不明なら nil です。
+func LookupSession(db *sql.DB, token string) (*User, error) {
+	if token == "" {
+		return nil, nil
+	}
+	var u User
+	err := db.QueryRow(`
+		SELECT u.id, u.username, u.is_admin
+		FROM sessions s JOIN users u ON s.user_id = u.id
+		WHERE s.token_hash = ? AND s.expires_at > ?`,
+		hashToken(token), time.Now().UTC().Format(time.RFC3339)).Scan(&u.ID, &u.Username, &u.IsAdmin)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
+func DeleteSession(db *sql.DB, token string) error {
+	_, err := db.Exec("DELETE FROM sessions WHERE token_hash = ?", hashToken(token))
+	return err
+}
+
+// PurgeExpiredSessions は期限切れのセッションを削除します。
+func PurgeExpiredSessions(db *sql.DB) error {
+	_, err := db.Exec("DELETE FROM sessions WHERE expires_at <= ?", time.Now().UTC().Format(time.RFC3339))
+	return err
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
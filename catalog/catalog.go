@@ -0,0 +1,208 @@
+// Package catalog はレシピの材料名を item_catalog の項目に解決します。
+// 名前・よみがなに加えて item_aliases の別名（「小ねぎ」→「青ネギ」）も見ます。
+// サーバーと tools の取込コマンドの両方から使います。
+package catalog
+
+import (
+	"database/sql"
+	"errors"
+	"math"
+	"sort"
+	"strings"
+
+	"kimichan/kana"
+)
+
+var ErrNotFound = errors.New("カタログに見つかりません")
+
+// RefTables は catalog_id で item_catalog を参照するテーブルです。項目を統合するときはすべて付け替えます。
+// refrigerator_seasonings も参照しますが、catalog_id ごとに1行なので統合先に行があれば捨てる必要があり、ここには入れません。
+var RefTables = []string{
+	"refrigerator_ingredients",
+	"recipe_ingredients",
+	"ingredient_events",
+	"shopping_list",
+	"item_aliases",
+}
+
+// *sql.DB と *sql.Tx のどちらからでも引けるようにするためのインターフェース
+type Querier interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// Match は材料名を解決した結果です。
+type Match struct {
+	ID      int    `json:"id"`
+	Name    string `json:"name"`
+	Alias   string `json:"alias,omitempty"`   // 別名で見つかったときの別名
+	Details string `json:"details,omitempty"` // 別名に設定された既定の詳細（「粉末」など）
+}
+
+// Resolve は材料名をカタログ項目に解決します。名前・よみがな・別名の順に探し、
+// どれにも当たらなければ ErrNotFound を返します。
+// 表記ゆれだけの違い（人参 ⇔ にんじん）でも、鮭 ⇔ 酒（どちらも さけ）のように別物のことがあるので、
+// Suggest の候補として返して使う人に選んでもらいます。
+func Resolve(q Querier, name string) (*Match, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, ErrNotFound
+	}
+
+	var m Match
+	err := q.QueryRow("SELECT id, name FROM item_catalog WHERE name = ? OR kana = ? ORDER BY name = ? DESC, id LIMIT 1",
+		name, name, name).Scan(&m.ID, &m.Name)
+	if err == nil {
+		return &m, nil
+	} else if err != sql.ErrNoRows {
+		return nil, err
+	}
+
+	err = q.QueryRow(`SELECT c.id, c.name, a.alias, COALESCE(a.details, '')
+		FROM item_aliases a JOIN item_catalog c ON a.catalog_id = c.id
+		WHERE a.alias = ?`, name).Scan(&m.ID, &m.Name, &m.Alias, &m.Details)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// JoinDetails は別名の既定の詳細を材料行の詳細の前に付けます（"粉末" + "大きめ" → "粉末 大きめ"）。
+func (m *Match) JoinDetails(details string) string {
+	details = strings.TrimSpace(details)
+	switch {
+	case m.Details == "":
+		return details
+	case details == "":
+		return m.Details
+	}
+	return m.Details + " " + details
+}
+
+// Candidate は見つからなかった材料名に近いカタログ項目です。
+type Candidate struct {
+	ID    int     `json:"id"`
+	Name  string  `json:"name"`
+	Kana  string  `json:"kana"`
+	Score float64 `json:"score"`           // 0〜1。1 が表記ゆれを除いて同じ
+	Match string  `json:"match"`           // exact / prefix / substring / similar
+	Alias string  `json:"alias,omitempty"` // 別名で近かったときの別名
+}
+
+// 候補にする最低の近さ
+const minSuggestScore = 0.5
+
+// 当たり方ごとの点数の上限。前方一致・部分一致は長さの比、似ているものは編集距離で割り引く
+var matchWeights = map[string]float64{"exact": 1, "prefix": 0.9, "substring": 0.8, "similar": 0.75}
+
+// Suggest は name に近いカタログ項目を近い順に limit 件まで返します。
+// 表記ゆれ（かな・カナ、全角・半角、漢字表記）を畳み込んだうえで、名前・よみがな・別名との
+// 一致・前方一致・部分一致・編集距離から近さを出します。
+func Suggest(q Querier, name string, limit int) ([]Candidate, error) {
+	target := kana.Fold(strings.TrimSpace(name))
+	if target == "" {
+		return []Candidate{}, nil
+	}
+
+	rows, err := q.Query(`SELECT c.id, c.name, COALESCE(c.kana, ''), COALESCE(a.alias, '')
+		FROM item_catalog c LEFT JOIN item_aliases a ON a.catalog_id = c.id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	best := make(map[int]*Candidate)
+	for rows.Next() {
+		var c Candidate
+		var alias string
+		if err := rows.Scan(&c.ID, &c.Name, &c.Kana, &alias); err != nil {
+			return nil, err
+		}
+		for i, s := range []string{c.Name, c.Kana, alias} {
+			score, match := similarity(target, kana.Fold(s))
+			if score < minSuggestScore {
+				continue
+			}
+			if b, ok := best[c.ID]; ok && b.Score >= score {
+				continue
+			}
+			cand := c
+			cand.Score = math.Round(score*100) / 100
+			cand.Match = match
+			if i == 2 {
+				cand.Alias = alias
+			}
+			best[c.ID] = &cand
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	list := make([]Candidate, 0, len(best))
+	for _, c := range best {
+		list = append(list, *c)
+	}
+	// 近い順、同じなら名前が短い順
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Score != list[j].Score {
+			return list[i].Score > list[j].Score
+		}
+		li, lj := len([]rune(list[i].Name)), len([]rune(list[j].Name))
+		if li != lj {
+			return li < lj
+		}
+		return list[i].ID < list[j].ID
+	})
+	if limit > 0 && len(list) > limit {
+		list = list[:limit]
+	}
+	return list, nil
+}
+
+// 畳み込み済みの2つの名前の近さ（0〜1）と当たり方。
+// 1文字どうしの前方一致・部分一致は拾いすぎるので、短い方が2文字以上のときだけ
+func similarity(target, s string) (float64, string) {
+	if s == "" {
+		return 0, ""
+	}
+	if s == target {
+		return matchWeights["exact"], "exact"
+	}
+	a, b := []rune(target), []rune(s)
+	shorter, longer := float64(min(len(a), len(b))), float64(max(len(a), len(b)))
+	if shorter >= 2 {
+		ratio := shorter / longer
+		switch {
+		case strings.HasPrefix(s, target) || strings.HasPrefix(target, s):
+			return matchWeights["prefix"] * (0.5 + ratio/2), "prefix"
+		case strings.Contains(s, target) || strings.Contains(target, s):
+			return matchWeights["substring"] * (0.5 + ratio/2), "substring"
+		}
+	}
+	d := levenshtein(a, b)
+	return matchWeights["similar"] * (1 - float64(d)/longer), "similar"
+}
+
+// 文字（rune）単位の編集距離
+func levenshtein(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,133 @@
+package catalog
+
+import (
+	"database/sql"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+
+	"kimichan/schema"
+)
+
+// マイグレーションを当てた空の DB に、テスト用のカタログ項目（id は 1 から順）と別名を入れる
+func newTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite3", schema.DSN(filepath.Join(t.TempDir(), "test.db")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if _, err := schema.Migrate(db); err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []struct{ name, kana string }{
+		{"玉ねぎ", "たまねぎ"}, {"新玉ねぎ", "しんたまねぎ"}, {"長ねぎ", "ながねぎ"}, {"青ネギ", "あおねぎ"},
+		{"にんじん", ""}, {"醤油", "しょうゆ"}, {"薄口醤油", "うすくちしょうゆ"}, {"じゃがいも", ""},
+		{"酒", "さけ"},
+	} {
+		if _, err := db.Exec("INSERT INTO item_catalog(name, kana, classification) VALUES(?, ?, '食材')", c.name, c.kana); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := db.Exec("INSERT INTO item_aliases(alias, catalog_id, details) SELECT '小ねぎ', id, '小口切り' FROM item_catalog WHERE name = '青ネギ'"); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestResolve(t *testing.T) {
+	db := newTestDB(t)
+	tests := []struct {
+		in   string
+		want *Match // nil なら ErrNotFound
+	}{
+		{"玉ねぎ", &Match{ID: 1, Name: "玉ねぎ"}},
+		{"たまねぎ", &Match{ID: 1, Name: "玉ねぎ"}}, // よみがな
+		{"小ねぎ", &Match{ID: 4, Name: "青ネギ", Alias: "小ねぎ", Details: "小口切り"}},
+		{"さけ", &Match{ID: 9, Name: "酒"}},
+		{"ねぎ", nil},
+		{"", nil},
+		// 表記ゆれだけの違いは候補（Suggest）にとどめ、勝手に解決しない
+		{"タマネギ", nil},
+		{"人参", nil},
+		{"鮭", nil}, // 畳み込むと 酒 の よみがな と同じ
+	}
+	for _, tt := range tests {
+		got, err := Resolve(db, tt.in)
+		if tt.want == nil {
+			if err != ErrNotFound {
+				t.Errorf("Resolve(%q) = %+v, %v, want ErrNotFound", tt.in, got, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Resolve(%q) error = %v", tt.in, err)
+			continue
+		}
+		if *got != *tt.want {
+			t.Errorf("Resolve(%q) = %+v, want %+v", tt.in, *got, *tt.want)
+		}
+	}
+}
+
+func TestSuggest(t *testing.T) {
+	db := newTestDB(t)
+
+	type want struct {
+		name  string
+		score float64
+		match string
+		alias string
+	}
+	tests := []struct {
+		in    string
+		limit int
+		want  []want
+	}{
+		{"タマネギ", 0, []want{{"玉ねぎ", 1, "exact", ""}, {"新玉ねぎ", 0.72, "substring", ""}}},
+		{"新たまねぎ", 0, []want{{"新玉ねぎ", 1, "exact", ""}, {"玉ねぎ", 0.72, "substring", ""}}},
+		// 同じ点数なら名前が短い順、それも同じなら id 順
+		{"ねぎ", 0, []want{
+			{"青ネギ", 0.67, "substring", ""}, {"玉ねぎ", 0.6, "substring", ""},
+			{"長ねぎ", 0.6, "substring", ""}, {"新玉ねぎ", 0.56, "substring", ""},
+		}},
+		{"ねぎ", 2, []want{{"青ネギ", 0.67, "substring", ""}, {"玉ねぎ", 0.6, "substring", ""}}},
+		{"濃口醤油", 0, []want{{"醤油", 0.67, "substring", ""}, {"薄口醤油", 0.63, "similar", ""}}},
+		{"小ねぎ", 0, []want{{"青ネギ", 1, "exact", "小ねぎ"}}},
+		{"鮭", 0, []want{{"酒", 1, "exact", ""}}},
+		{"たまねき", 0, []want{{"玉ねぎ", 0.56, "similar", ""}}},
+		{"玉", 0, nil}, // 1文字の部分一致は拾わない
+		{"かぼちゃ", 0, nil},
+	}
+	for _, tt := range tests {
+		got, err := Suggest(db, tt.in, tt.limit)
+		if err != nil {
+			t.Fatalf("Suggest(%q): %v", tt.in, err)
+		}
+		var gotWant []want
+		for _, c := range got {
+			gotWant = append(gotWant, want{c.Name, c.Score, c.Match, c.Alias})
+		}
+		if !reflect.DeepEqual(gotWant, tt.want) {
+			t.Errorf("Suggest(%q, %d) = %v, want %v", tt.in, tt.limit, gotWant, tt.want)
+		}
+	}
+}
+
+func TestJoinDetails(t *testing.T) {
+	tests := []struct {
+		alias, line, want string
+	}{
+		{"", "大きめ", "大きめ"},
+		{"粉末", "", "粉末"},
+		{"粉末", " 大きめ ", "粉末 大きめ"},
+	}
+	for _, tt := range tests {
+		m := &Match{Details: tt.alias}
+		if got := m.JoinDetails(tt.line); got != tt.want {
+			t.Errorf("JoinDetails(%q, %q) = %q, want %q", tt.alias, tt.line, got, tt.want)
+		}
+	}
+}
@@ -5,6 +5,7 @@ go 1.24.3
 require (
 	github.com/PuerkitoBio/goquery v1.11.0
 	github.com/mattn/go-sqlite3 v1.14.32
+	golang.org/x/crypto v0.45.0
 )
 
 require (
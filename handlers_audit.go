@@ -0,0 +1,225 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// 監査ログの対象
+const (
+	AuditIngredient = "ingredient"
+	AuditSeasoning  = "seasoning"
+	AuditCatalog    = "catalog"
+	AuditRecipe     = "recipe"
+	AuditAlias      = "alias"
+)
+
+// 監査ログの操作
+const (
+	AuditCreate  = "create"
+	AuditUpdate  = "update"
+	AuditDelete  = "delete"
+	AuditMerge   = "merge"
+	AuditUnmerge = "unmerge"
+)
+
+type AuditEntry struct {
+	ID         int             `json:"id"`
+	UserID     *int            `json:"user_id"`
+	Actor      string          `json:"actor"`
+	EntityType string          `json:"entity_type"`
+	EntityID   int             `json:"entity_id"`
+	Action     string          `json:"action"`
+	Before     json.RawMessage `json:"before"`
+	After      json.RawMessage `json:"after"`
+	CreatedAt  string          `json:"created_at"`
+}
+
+// 変更と同じトランザクションで監査ログを書く。before / after は nil 可
+func writeAudit(tx *sql.Tx, r *http.Request, entityType string, entityID int, action string, before, after interface{}) error {
+	var userID interface{}
+	actor := "system"
+	if u := currentUser(r); u != nil {
+		userID = u.ID
+		actor = u.Username
+	}
+
+	beforeJSON, err := marshalSnapshot(before)
+	if err != nil {
+		return err
+	}
+	afterJSON, err := marshalSnapshot(after)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("INSERT INTO audit_log(user_id, actor, entity_type, entity_id, action, before_json, after_json) VALUES(?, ?, ?, ?, ?, ?, ?)",
+		userID, actor, entityType, entityID, action, beforeJSON, afterJSON)
+	if err != nil {
+		return fmt.Errorf("audit log error: %w", err)
+	}
+	return nil
+}
+
+func marshalSnapshot(v interface{}) (interface{}, error) {
+	if v == nil {
+		return nil, nil
+	}
+	if m, ok := v.(map[string]interface{}); ok && m == nil {
+		return nil, nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+// 1行をカラム名 → 値の map で取得する（見つからなければ nil）
+func snapshotRow(tx *sql.Tx, table string, id int) (map[string]interface{}, error) {
+	rows, err := snapshotRows(tx, "SELECT * FROM "+table+" WHERE id = ?", id)
+	if err != nil || len(rows) == 0 {
+		return nil, err
+	}
+	return rows[0], nil
+}
+
+func snapshotRows(tx *sql.Tx, query string, args ...interface{}) ([]map[string]interface{}, error) {
+	rows, err := tx.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	cols, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	list := []map[string]interface{}{}
+	for rows.Next() {
+		values := make([]interface{}, len(cols))
+		ptrs := make([]interface{}, len(cols))
+		for i := range values {
+			ptrs[i] = &values[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return nil, err
+		}
+		m := make(map[string]interface{}, len(cols))
+		for i, c := range cols {
+			if b, ok := values[i].([]byte); ok {
+				m[c] = string(b)
+			} else {
+				m[c] = values[i]
+			}
+		}
+		list = append(list, m)
+	}
+	return list, rows.Err()
+}
+
+// レシピ本体と材料をまとめたスナップショット
+func snapshotRecipe(tx *sql.Tx, id int) (map[string]interface{}, error) {
+	recipe, err := snapshotRow(tx, "recipes", id)
+	if err != nil || recipe == nil {
+		return nil, err
+	}
+	ings, err := snapshotRows(tx, "SELECT * FROM recipe_ingredients WHERE recipe_id = ? ORDER BY id", id)
+	if err != nil {
+		return nil, err
+	}
+	recipe["ingredients"] = ings
+	return recipe, nil
+}
+
+// GET /api/audit?entity=ingredient&entity_id=3&since=2026-01-01&limit=100
+func handleAudit(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		sendJSONError(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	q := r.URL.Query()
+
+	query := "SELECT id, user_id, actor, entity_type, entity_id, action, before_json, after_json, created_at FROM audit_log WHERE 1=1"
+	var args []interface{}
+
+	if entity := q.Get("entity"); entity != "" {
+		query += " AND entity_type = ?"
+		args = append(args, entity)
+	}
+	if idStr := q.Get("entity_id"); idStr != "" {
+		id, err := strconv.Atoi(idStr)
+		if err != nil {
+			sendJSONError(w, "entity_id must be a number", http.StatusBadRequest)
+			return
+		}
+		query += " AND entity_id = ?"
+		args = append(args, id)
+	}
+	if actor := q.Get("actor"); actor != "" {
+		query += " AND actor = ?"
+		args = append(args, actor)
+	}
+	if since := q.Get("since"); since != "" {
+		t, err := parseSince(since)
+		if err != nil {
+			sendJSONError(w, "since は YYYY-MM-DD または RFC3339 形式で指定してください", http.StatusBadRequest)
+			return
+		}
+		query += " AND created_at >= ?"
+		args = append(args, t.UTC().Format("2006-01-02 15:04:05"))
+	}
+
+	limit := 200
+	if l, err := strconv.Atoi(q.Get("limit")); err == nil && l > 0 && l <= 1000 {
+		limit = l
+	}
+	query += " ORDER BY id DESC LIMIT ?"
+	args = append(args, limit)
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		sendJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	entries := []AuditEntry{}
+	for rows.Next() {
+		var e AuditEntry
+		var userID sql.NullInt64
+		var before, after sql.NullString
+		if err := rows.Scan(&e.ID, &userID, &e.Actor, &e.EntityType, &e.EntityID, &e.Action, &before, &after, &e.CreatedAt); err != nil {
+			sendJSONError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if userID.Valid {
+			id := int(userID.Int64)
+			e.UserID = &id
+		}
+		e.Before = rawJSONOrNull(before)
+		e.After = rawJSONOrNull(after)
+		entries = append(entries, e)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(entries)
+}
+
+func parseSince(s string) (time.Time, error) {
+	if t, err := time.ParseInLocation("2006-01-02", s, time.Local); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
+
+func rawJSONOrNull(ns sql.NullString) json.RawMessage {
+	if !ns.Valid || ns.String == "" {
+		return json.RawMessage("null")
+	}
+	return json.RawMessage(ns.String)
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+
+	"kimichan/accounts"
+)
+
+const sessionCookieName = "kimichan_session"
+
+type ctxKey int
+
+const userCtxKey ctxKey = 0
+
+// ログイン無しで見られるパス（ログイン画面とそのスタイル）
+var publicPaths = []string{"/login.html", "/css/", "/api/login"}
+
+// 初回起動時、users が空なら環境変数から管理者を作る。
+// Docker イメージに既定のパスワードを入れないための仕組み
+func bootstrapAdmin() error {
+	count, err := accounts.CountUsers(db)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+
+	user := os.Getenv("KIMICHAN_ADMIN_USER")
+	pass := os.Getenv("KIMICHAN_ADMIN_PASSWORD")
+	if user == "" || pass == "" {
+		log.Println("⚠️ ユーザーが登録されていません。KIMICHAN_ADMIN_USER / KIMICHAN_ADMIN_PASSWORD を設定するか、`go run ./tools/user add <名前>` で作成してください")
+		return nil
+	}
+	if _, err := accounts.CreateUser(db, user, pass, true); err != nil {
+		return fmt.Errorf("管理者の作成に失敗しました: %w", err)
+	}
+	log.Printf("管理者ユーザー「%s」を作成しました", user)
+	return nil
+}
+
+// 認証ミドルウェア。セッションクッキーを優先し、無ければ Basic 認証（curl やツール用）を見る
+func authMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, p := range publicPaths {
+			if strings.HasPrefix(r.URL.Path, p) {
+				next.ServeHTTP(w, r)
+				return
+			}
+		}
+
+		user, err := userFromRequest(r)
+		if err != nil {
+			sendJSONError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if user == nil {
+			if strings.HasPrefix(r.URL.Path, "/api/") || strings.HasPrefix(r.URL.Path, "/import/") {
+				sendJSONError(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+			http.Redirect(w, r, "/login.html", http.StatusSeeOther)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), userCtxKey, user)))
+	})
+}
+
+func userFromRequest(r *http.Request) (*accounts.User, error) {
+	if c, err := r.Cookie(sessionCookieName); err == nil {
+		user, err := accounts.LookupSession(db, c.Value)
+		if err != nil || user != nil {
+			return user, err
+		}
+	}
+	if name, pass, ok := r.BasicAuth(); ok {
+		user, err := accounts.Authenticate(db, name, pass)
+		if err == accounts.ErrInvalidCredentials {
+			return nil, nil
+		}
+		return user, err
+	}
+	return nil, nil
+}
+
+// ログイン中のユーザー（認証ミドルウェアを通ったリクエストなら必ずある）
+func currentUser(r *http.Request) *accounts.User {
+	u, _ := r.Context().Value(userCtxKey).(*accounts.User)
+	return u
+}
+
+func handleLogin(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		sendJSONError(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	user, err := accounts.Authenticate(db, req.Username, req.Password)
+	if err == accounts.ErrInvalidCredentials {
+		sendJSONError(w, err.Error(), http.StatusUnauthorized)
+		return
+	} else if err != nil {
+		sendJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	accounts.PurgeExpiredSessions(db)
+	token, expires, err := accounts.CreateSession(db, user.ID)
+	if err != nil {
+		sendJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookieName,
+		Value:    token,
+		Path:     "/",
+		Expires:  expires,
+		HttpOnly: true,
+		Secure:   isSecureRequest(r),
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(user)
+}
+
+func handleLogout(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		sendJSONError(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if c, err := r.Cookie(sessionCookieName); err == nil {
+		accounts.DeleteSession(db, c.Value)
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookieName,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   isSecureRequest(r),
+		SameSite: http.SameSiteLaxMode,
+	})
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "logged_out"})
+}
+
+func handleMe(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(currentUser(r))
+}
+
+// Cloud Run などプロキシ越しの HTTPS も考慮する
+func isSecureRequest(r *http.Request) bool {
+	return r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https"
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"database/sql"
+	"math"
+
+	"kimichan/quantity"
+	"kimichan/units"
+)
+
+// 材料1つぶんの在庫判定
+const (
+	StockEnough  = "enough"  // 必要量を満たしている（分量指定なしで在庫ありも含む）
+	StockShort   = "short"   // 在庫はあるが足りない
+	StockMissing = "missing" // 在庫なし
+	StockUnknown = "unknown" // 在庫はあるが量が不明・単位が換算できない
+)
+
+// レシピ全体の判定
+const (
+	RecipeMakeable          = "makeable"
+	RecipePartiallyMakeable = "partially_makeable" // 足りない・無い材料がある
+	RecipeUnknownUnits      = "unknown_units"      // 不足は無いが、量を比べられない材料がある
+)
+
+// 必要量・在庫量・不足量はいずれもレシピ側の単位で表す
+type IngredientAvailability struct {
+	Status    string   `json:"status"`
+	Required  *float64 `json:"required,omitempty"`
+	Available *float64 `json:"available,omitempty"`
+	Shortfall *float64 `json:"shortfall,omitempty"`
+	Unit      string   `json:"unit,omitempty"`
+}
+
+type stockRow struct {
+	Amount float64
+	Unit   string
+}
+
+// 在庫量の判定に使うスナップショット。換算係数は必要になった項目だけ読む
+type inventoryStock struct {
+	rows       map[int][]stockRow
+	seasonings map[int]bool
+	factors    map[int]units.Factors
+}
+
+func loadInventoryStock() (*inventoryStock, error) {
+	s := &inventoryStock{
+		rows:    make(map[int][]stockRow),
+		factors: make(map[int]units.Factors),
+	}
+
+	rows, err := db.Query("SELECT catalog_id, amount, unit FROM refrigerator_ingredients")
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var cid int
+		var row stockRow
+		var unit sql.NullString
+		if err := rows.Scan(&cid, &row.Amount, &unit); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		row.Unit = unit.String
+		s.rows[cid] = append(s.rows[cid], row)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	seasonings, err := loadSeasoningStock()
+	if err != nil {
+		return nil, err
+	}
+	s.seasonings = seasonings
+	return s, nil
+}
+
+func (s *inventoryStock) conversionFactors(catalogID int) units.Factors {
+	if f, ok := s.factors[catalogID]; ok {
+		return f
+	}
+	// 読めなければ係数なし（体積どうし・重さどうしだけ換算する）
+	f, _ := loadConversionFactors(db, catalogID)
+	s.factors[catalogID] = f
+	return f
+}
+
+// 必要量 req と在庫の合計を比べる。調味料はストック状態だけで判定する
+func (s *inventoryStock) check(catalogID int, classification string, req quantity.Quantity) IngredientAvailability {
+	if classification == "調味料" {
+		if s.seasonings[catalogID] {
+			return IngredientAvailability{Status: StockEnough}
+		}
+		return IngredientAvailability{Status: StockMissing}
+	}
+
+	rows := s.rows[catalogID]
+	if len(rows) == 0 {
+		a := IngredientAvailability{Status: StockMissing}
+		if req.HasValue() {
+			a.Required = floatPtr(req.Value)
+			a.Available = floatPtr(0)
+			a.Shortfall = floatPtr(req.Value)
+			a.Unit = req.Unit
+		}
+		return a
+	}
+	// 「適量」「少々」など数値の無い分量は、在庫があれば足りるとみなす
+	if !req.HasValue() {
+		return IngredientAvailability{Status: StockEnough}
+	}
+
+	// 量が不明な行（amount = -1）や換算できない行は合計に入れず、不明として覚えておく
+	factors := s.conversionFactors(catalogID)
+	total := 0.0
+	uncounted := false
+	for _, row := range rows {
+		if row.Amount < 0 {
+			uncounted = true
+			continue
+		}
+		converted, err := units.Convert(row.Amount, row.Unit, req.Unit, factors)
+		if err != nil {
+			uncounted = true
+			continue
+		}
+		total += converted.Value
+	}
+	total = roundAmount(total)
+
+	a := IngredientAvailability{
+		Required:  floatPtr(req.Value),
+		Available: floatPtr(total),
+		Unit:      req.Unit,
+	}
+	switch {
+	case total >= req.Value:
+		a.Status = StockEnough
+	case uncounted:
+		a.Status = StockUnknown
+	default:
+		a.Status = StockShort
+		a.Shortfall = floatPtr(roundAmount(req.Value - total))
+	}
+	return a
+}
+
+// 材料ごとの判定からレシピ全体の判定を出す（不足があればそちらを優先）
+func recipeAvailability(items []IngredientAvailability) string {
+	status := RecipeMakeable
+	for _, a := range items {
+		switch a.Status {
+		case StockShort, StockMissing:
+			return RecipePartiallyMakeable
+		case StockUnknown:
+			status = RecipeUnknownUnits
+		}
+	}
+	return status
+}
+
+// recipe_ingredients の quantity_* カラムから分量を組み立てる
+func quantityFromColumns(value, max sql.NullFloat64, unit, qualifier sql.NullString) quantity.Quantity {
+	return quantity.Quantity{
+		Value:     value.Float64,
+		Max:       max.Float64,
+		Unit:      unit.String,
+		Qualifier: qualifier.String,
+	}
+}
+
+// 換算で出る 0.30000000000000004 のような端数を丸める
+func roundAmount(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+func floatPtr(v float64) *float64 {
+	return &v
+}
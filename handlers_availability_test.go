@@ -0,0 +1,97 @@
+package main
+
+import (
+	"testing"
+
+	"kimichan/quantity"
+	"kimichan/units"
+)
+
+func TestInventoryStockCheck(t *testing.T) {
+	// 1: 玉ねぎ（個、1個 200g） 2: 牛乳（ml、密度 1.03） 3: 醤油（調味料） 4: 卵（在庫なし） 5: 鶏肉（量不明の在庫あり）
+	stock := &inventoryStock{
+		rows: map[int][]stockRow{
+			1: {{Amount: 1, Unit: "個"}, {Amount: 300, Unit: "g"}},
+			2: {{Amount: 1, Unit: "L"}},
+			5: {{Amount: -1, Unit: "g"}, {Amount: 100, Unit: "g"}},
+		},
+		seasonings: map[int]bool{3: true},
+		factors: map[int]units.Factors{
+			1: {PieceUnit: "個", PieceGrams: 200},
+			2: {Density: 1.03},
+			4: {},
+			5: {},
+		},
+	}
+	qty := func(s string) quantity.Quantity {
+		q, err := quantity.Parse(s)
+		if err != nil {
+			t.Fatalf("quantity.Parse(%q): %v", s, err)
+		}
+		return q
+	}
+
+	tests := []struct {
+		name           string
+		catalogID      int
+		classification string
+		req            quantity.Quantity
+		status         string
+		available      float64
+		shortfall      float64
+	}{
+		{"個とgを合わせて足りる", 1, "食材", qty("2個"), StockEnough, 2.5, 0},
+		{"個とgを合わせても足りない", 1, "食材", qty("3個"), StockShort, 2.5, 0.5},
+		{"重さで比べる", 1, "食材", qty("500g"), StockEnough, 500, 0},
+		{"体積どうし", 2, "食材", qty("カップ2"), StockEnough, 5, 0},
+		{"分量なしは在庫があれば足りる", 1, "食材", qty("適量"), StockEnough, 0, 0},
+		{"在庫なし", 4, "食材", qty("2個"), StockMissing, 0, 2},
+		{"量不明の在庫がある", 5, "食材", qty("200g"), StockUnknown, 100, 0},
+		{"量不明の在庫があっても数えた分で足りる", 5, "食材", qty("100g"), StockEnough, 100, 0},
+		{"調味料はストック状態", 3, "調味料", qty("大さじ2"), StockEnough, 0, 0},
+		{"調味料のストックなし", 6, "調味料", qty("少々"), StockMissing, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := stock.check(tt.catalogID, tt.classification, tt.req)
+			if got.Status != tt.status {
+				t.Fatalf("status = %s, want %s", got.Status, tt.status)
+			}
+			if got.Available != nil && *got.Available != tt.available {
+				t.Errorf("available = %v, want %v", *got.Available, tt.available)
+			}
+			var shortfall float64
+			if got.Shortfall != nil {
+				shortfall = *got.Shortfall
+			}
+			if shortfall != tt.shortfall {
+				t.Errorf("shortfall = %v, want %v", shortfall, tt.shortfall)
+			}
+			if got.Required != nil && got.Unit != tt.req.Unit {
+				t.Errorf("unit = %q, want the recipe unit %q", got.Unit, tt.req.Unit)
+			}
+		})
+	}
+}
+
+func TestRecipeAvailability(t *testing.T) {
+	tests := []struct {
+		statuses []string
+		want     string
+	}{
+		{nil, RecipeMakeable},
+		{[]string{StockEnough, StockEnough}, RecipeMakeable},
+		{[]string{StockEnough, StockUnknown}, RecipeUnknownUnits},
+		{[]string{StockUnknown, StockShort}, RecipePartiallyMakeable},
+		{[]string{StockMissing, StockEnough}, RecipePartiallyMakeable},
+	}
+	for _, tt := range tests {
+		items := make([]IngredientAvailability, len(tt.statuses))
+		for i, s := range tt.statuses {
+			items[i].Status = s
+		}
+		if got := recipeAvailability(items); got != tt.want {
+			t.Errorf("recipeAvailability(%v) = %s, want %s", tt.statuses, got, tt.want)
+		}
+	}
+}
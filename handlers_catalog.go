@@ -30,13 +30,13 @@ func handleCatalogUsage(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var recipeCount int
-	err := db.QueryRow("SELECT COUNT(DISTINCT recipe_id) FROM recipe_ingredients WHERE catalog_id = ?", idStr).Scan(&recipeCount)
+	err := db.QueryRow("SELECT COUNT(DISTINCT ri.recipe_id) FROM recipe_ingredients ri JOIN recipes r ON ri.recipe_id = r.id WHERE ri.catalog_id = ? AND r.deleted_at IS NULL", idStr).Scan(&recipeCount)
 	if err != nil {
 		sendJSONError(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	rows, err := db.Query("SELECT DISTINCT r.name FROM recipes r JOIN recipe_ingredients ri ON r.id = ri.recipe_id WHERE ri.catalog_id = ? LIMIT 3", idStr)
+	rows, err := db.Query("SELECT DISTINCT r.name FROM recipes r JOIN recipe_ingredients ri ON r.id = ri.recipe_id WHERE ri.catalog_id = ? AND r.deleted_at IS NULL LIMIT 3", idStr)
 	if err != nil {
 		sendJSONError(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -60,7 +60,9 @@ func handleCatalogUsage(w http.ResponseWriter, r *http.Request) {
 }
 
 func getCatalogItems(w http.ResponseWriter, _ *http.Request) {
-	rows, err := db.Query("SELECT id, name, kana, classification, category, default_unit FROM item_catalog ORDER BY name ASC")
+	rows, err := db.Query(`SELECT id, name, kana, classification, category, default_unit,
+		shelf_life_fridge, shelf_life_freezer, shelf_life_room, density, piece_unit, piece_grams
+		FROM item_catalog ORDER BY name ASC`)
 	if err != nil {
 		sendJSONError(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -71,7 +73,9 @@ func getCatalogItems(w http.ResponseWriter, _ *http.Request) {
 	for rows.Next() {
 		var item CatalogItem
 		var kana sql.NullString
-		if err := rows.Scan(&item.ID, &item.Name, &kana, &item.Classification, &item.Category, &item.DefaultUnit); err != nil {
+		if err := rows.Scan(&item.ID, &item.Name, &kana, &item.Classification, &item.Category, &item.DefaultUnit,
+			&item.ShelfLifeFridge, &item.ShelfLifeFreezer, &item.ShelfLifeRoom,
+			&item.Density, &item.PieceUnit, &item.PieceGrams); err != nil {
 			sendJSONError(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
@@ -96,13 +100,20 @@ func addCatalogItems(w http.ResponseWriter, r *http.Request) {
 	}
 
 	query := `
-	INSERT INTO item_catalog(name, kana, classification, category, default_unit) 
-	VALUES(?, ?, ?, ?, ?)
+	INSERT INTO item_catalog(name, kana, classification, category, default_unit, shelf_life_fridge, shelf_life_freezer, shelf_life_room,
+		density, piece_unit, piece_grams) 
+	VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	ON CONFLICT(name) DO UPDATE SET
 	kana = excluded.kana,
 	classification = excluded.classification,
 	category = excluded.category,
-	default_unit = excluded.default_unit
+	default_unit = excluded.default_unit,
+	shelf_life_fridge = COALESCE(excluded.shelf_life_fridge, shelf_life_fridge),
+	shelf_life_freezer = COALESCE(excluded.shelf_life_freezer, shelf_life_freezer),
+	shelf_life_room = COALESCE(excluded.shelf_life_room, shelf_life_room),
+	density = COALESCE(excluded.density, density),
+	piece_unit = COALESCE(excluded.piece_unit, piece_unit),
+	piece_grams = COALESCE(excluded.piece_grams, piece_grams)
 	`
 	stmt, err := tx.Prepare(query)
 	if err != nil {
@@ -118,12 +129,32 @@ func addCatalogItems(w http.ResponseWriter, r *http.Request) {
 			sendJSONError(w, "name required", http.StatusBadRequest)
 			return
 		}
-		_, err := stmt.Exec(item.Name, item.Kana, item.Classification, item.Category, item.DefaultUnit)
+
+		// 既存なら上書き（監査ログ用に変更前を取っておく）
+		var before map[string]interface{}
+		var existingID int
+		if err := tx.QueryRow("SELECT id FROM item_catalog WHERE name = ?", item.Name).Scan(&existingID); err == nil {
+			if before, err = snapshotRow(tx, "item_catalog", existingID); err != nil {
+				tx.Rollback()
+				sendJSONError(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+
+		_, err := stmt.Exec(item.Name, item.Kana, item.Classification, item.Category, item.DefaultUnit,
+			optionalColumnArg(item.ShelfLifeFridge, nil), optionalColumnArg(item.ShelfLifeFreezer, nil), optionalColumnArg(item.ShelfLifeRoom, nil),
+			optionalColumnArg(item.Density, nil), optionalColumnArg(item.PieceUnit, nil), optionalColumnArg(item.PieceGrams, nil))
 		if err != nil {
 			tx.Rollback()
 			sendJSONError(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
+
+		if err := auditCatalogUpsert(tx, r, item.Name, before); err != nil {
+			tx.Rollback()
+			sendJSONError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
 	}
 
 	if err := tx.Commit(); err != nil {
@@ -142,7 +173,14 @@ func updateCatalogItem(w http.ResponseWriter, r *http.Request) {
 		Classification string `json:"classification"`
 		Category       string `json:"category"`
 		DefaultUnit    string `json:"default_unit"`
-		ForceMerge     bool   `json:"force_merge"`
+		// 省略時は変更なし、0 なら未設定に戻す
+		ShelfLifeFridge  *int     `json:"shelf_life_fridge"`
+		ShelfLifeFreezer *int     `json:"shelf_life_freezer"`
+		ShelfLifeRoom    *int     `json:"shelf_life_room"`
+		Density          *float64 `json:"density"`
+		PieceUnit        *string  `json:"piece_unit"`
+		PieceGrams       *float64 `json:"piece_grams"`
+		ForceMerge       bool     `json:"force_merge"`
 	}
 	var req UpdateReq
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -156,6 +194,18 @@ func updateCatalogItem(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	before, err := snapshotRow(tx, "item_catalog", req.ID)
+	if err != nil {
+		tx.Rollback()
+		sendJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if before == nil {
+		tx.Rollback()
+		sendJSONError(w, "not found", http.StatusNotFound)
+		return
+	}
+
 	var targetID int
 	err = tx.QueryRow("SELECT id FROM item_catalog WHERE name = ? AND id != ?", req.Name, req.ID).Scan(&targetID)
 
@@ -172,19 +222,46 @@ func updateCatalogItem(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		// 統合処理
-		tx.Exec("UPDATE refrigerator_ingredients SET catalog_id = ? WHERE catalog_id = ?", targetID, req.ID)
-		tx.Exec("UPDATE recipe_ingredients SET catalog_id = ? WHERE catalog_id = ?", targetID, req.ID)
-		// 調味料テーブルは無視
-		tx.Exec("DELETE FROM item_catalog WHERE id = ?", req.ID)
+		// 統合処理（取り消せるように移動した行を記録する）
+		mergeID, err := mergeCatalogItem(tx, r, req.ID, targetID, before)
+		if err != nil {
+			tx.Rollback()
+			sendJSONError(w, "統合失敗: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if err := tx.Commit(); err != nil {
+			sendJSONError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{"status": "merged", "merge_id": mergeID, "target_id": targetID})
+		return
 
 	} else {
-		query := `UPDATE item_catalog SET name=?, kana=?, classification=?, category=?, default_unit=? WHERE id=?`
-		if _, err := tx.Exec(query, req.Name, req.Kana, req.Classification, req.Category, req.DefaultUnit, req.ID); err != nil {
+		query := `UPDATE item_catalog SET name=?, kana=?, classification=?, category=?, default_unit=?,
+			shelf_life_fridge=?, shelf_life_freezer=?, shelf_life_room=?, density=?, piece_unit=?, piece_grams=? WHERE id=?`
+		if _, err := tx.Exec(query, req.Name, req.Kana, req.Classification, req.Category, req.DefaultUnit,
+			optionalColumnArg(req.ShelfLifeFridge, before["shelf_life_fridge"]),
+			optionalColumnArg(req.ShelfLifeFreezer, before["shelf_life_freezer"]),
+			optionalColumnArg(req.ShelfLifeRoom, before["shelf_life_room"]),
+			optionalColumnArg(req.Density, before["density"]),
+			optionalColumnArg(req.PieceUnit, before["piece_unit"]),
+			optionalColumnArg(req.PieceGrams, before["piece_grams"]),
+			req.ID); err != nil {
 			tx.Rollback()
 			sendJSONError(w, "更新失敗: "+err.Error(), http.StatusInternalServerError)
 			return
 		}
+
+		after, err := snapshotRow(tx, "item_catalog", req.ID)
+		if err == nil {
+			err = writeAudit(tx, r, AuditCatalog, req.ID, AuditUpdate, before, after)
+		}
+		if err != nil {
+			tx.Rollback()
+			sendJSONError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
 	}
 
 	if err := tx.Commit(); err != nil {
@@ -204,7 +281,7 @@ func deleteCatalogItem(w http.ResponseWriter, r *http.Request) {
 	var id int
 	fmt.Sscanf(idStr, "%d", &id)
 
-	// 使用チェック (調味料テーブルは見ない)
+	// 使用チェック
 	var count int
 	db.QueryRow("SELECT count(*) FROM recipe_ingredients WHERE catalog_id = ?", id).Scan(&count)
 	if count > 0 {
@@ -216,12 +293,85 @@ func deleteCatalogItem(w http.ResponseWriter, r *http.Request) {
 		sendJSONError(w, "在庫にあるため削除できません", http.StatusConflict)
 		return
 	}
+	db.QueryRow("SELECT count(*) FROM refrigerator_seasonings WHERE catalog_id = ?", id).Scan(&count)
+	if count > 0 {
+		sendJSONError(w, "調味料ストックにあるため削除できません", http.StatusConflict)
+		return
+	}
+	db.QueryRow("SELECT count(*) FROM shopping_list WHERE catalog_id = ?", id).Scan(&count)
+	if count > 0 {
+		sendJSONError(w, "買い物リストにあるため削除できません", http.StatusConflict)
+		return
+	}
+	db.QueryRow("SELECT count(*) FROM ingredient_events WHERE catalog_id = ?", id).Scan(&count)
+	if count > 0 {
+		sendJSONError(w, "在庫の履歴があるため削除できません（別の項目への統合はできます）", http.StatusConflict)
+		return
+	}
 
-	_, err := db.Exec("DELETE FROM item_catalog WHERE id = ?", id)
+	tx, err := db.Begin()
+	if err != nil {
+		sendJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	before, err := snapshotRow(tx, "item_catalog", id)
 	if err != nil {
+		tx.Rollback()
+		sendJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	// 別名は項目と一緒に消す
+	if _, err := tx.Exec("DELETE FROM item_aliases WHERE catalog_id = ?", id); err != nil {
+		tx.Rollback()
+		sendJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if _, err := tx.Exec("DELETE FROM item_catalog WHERE id = ?", id); err != nil {
+		tx.Rollback()
+		sendJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if before != nil {
+		if err := writeAudit(tx, r, AuditCatalog, id, AuditDelete, before, nil); err != nil {
+			tx.Rollback()
+			sendJSONError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+	if err := tx.Commit(); err != nil {
 		sendJSONError(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(map[string]string{"status": "deleted"})
 }
+
+// 名前で登録・上書きしたカタログ項目の監査ログを書く（before が nil なら新規作成）
+func auditCatalogUpsert(tx *sql.Tx, r *http.Request, name string, before map[string]interface{}) error {
+	var id int
+	if err := tx.QueryRow("SELECT id FROM item_catalog WHERE name = ?", name).Scan(&id); err != nil {
+		return err
+	}
+	after, err := snapshotRow(tx, "item_catalog", id)
+	if err != nil {
+		return err
+	}
+	action := AuditCreate
+	if before != nil {
+		action = AuditUpdate
+	}
+	return writeAudit(tx, r, AuditCatalog, id, action, before, after)
+}
+
+// PUT/POST で受け取った任意項目（日持ち日数・換算係数）を保存用の値にする
+// nil は変更なし（current をそのまま）、ゼロ値は未設定（NULL）に戻す
+func optionalColumnArg[T int | float64 | string](v *T, current interface{}) interface{} {
+	if v == nil {
+		return current
+	}
+	var zero T
+	if *v == zero {
+		return nil
+	}
+	return *v
+}
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+type CatalogAlias struct {
+	ID          int    `json:"id"`
+	Alias       string `json:"alias"`
+	CatalogID   int    `json:"catalog_id"`
+	CatalogName string `json:"catalog_name"`
+	Details     string `json:"details"`
+	CreatedAt   string `json:"created_at"`
+}
+
+// GET    /api/catalog/aliases?catalog_id=3  別名の一覧（catalog_id で絞り込み）
+// POST   /api/catalog/aliases {"alias": "小ねぎ", "catalog_id": 3, "details": "小ネギ"}
+// PUT    /api/catalog/aliases {"id": 1, "alias": ..., "catalog_id": ..., "details": ...}
+// DELETE /api/catalog/aliases?id=1
+// catalog_id の代わりに catalog_name で指定してもよい
+func handleCatalogAliases(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		getCatalogAliases(w, r)
+	case "POST", "PUT":
+		saveCatalogAlias(w, r)
+	case "DELETE":
+		deleteCatalogAlias(w, r)
+	default:
+		sendJSONError(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func getCatalogAliases(w http.ResponseWriter, r *http.Request) {
+	query := `SELECT a.id, a.alias, a.catalog_id, c.name, COALESCE(a.details, ''), a.created_at
+		FROM item_aliases a JOIN item_catalog c ON a.catalog_id = c.id`
+	var args []interface{}
+	if s := r.URL.Query().Get("catalog_id"); s != "" {
+		id, err := strconv.Atoi(s)
+		if err != nil {
+			sendJSONError(w, "catalog_id が不正です", http.StatusBadRequest)
+			return
+		}
+		query += " WHERE a.catalog_id = ?"
+		args = append(args, id)
+	}
+	rows, err := db.Query(query+" ORDER BY c.name, a.alias", args...)
+	if err != nil {
+		sendJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	aliases := []CatalogAlias{}
+	for rows.Next() {
+		var a CatalogAlias
+		if err := rows.Scan(&a.ID, &a.Alias, &a.CatalogID, &a.CatalogName, &a.Details, &a.CreatedAt); err != nil {
+			sendJSONError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		aliases = append(aliases, a)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(aliases)
+}
+
+func saveCatalogAlias(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ID          int    `json:"id"`
+		Alias       string `json:"alias"`
+		CatalogID   int    `json:"catalog_id"`
+		CatalogName string `json:"catalog_name"`
+		Details     string `json:"details"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	req.Alias = strings.TrimSpace(req.Alias)
+	req.Details = strings.TrimSpace(req.Details)
+	if req.Alias == "" {
+		sendJSONError(w, "alias は必須です", http.StatusBadRequest)
+		return
+	}
+	if r.Method == "PUT" && req.ID == 0 {
+		sendJSONError(w, "id required", http.StatusBadRequest)
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		sendJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	catalogID, err := resolveAliasTarget(tx, req.CatalogID, req.CatalogName)
+	if err == sql.ErrNoRows {
+		tx.Rollback()
+		sendJSONError(w, "カタログ項目が見つかりません", http.StatusNotFound)
+		return
+	} else if err != nil {
+		tx.Rollback()
+		sendJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// カタログの名前は別名より先に当たるので、別名にしても使われない
+	var existingID int
+	if err := tx.QueryRow("SELECT id FROM item_catalog WHERE name = ?", req.Alias).Scan(&existingID); err == nil {
+		tx.Rollback()
+		sendJSONError(w, fmt.Sprintf("「%s」はカタログに登録済みの名前です（id=%d）。統合するとその名前も使えます", req.Alias, existingID), http.StatusConflict)
+		return
+	}
+	if err := tx.QueryRow("SELECT id FROM item_aliases WHERE alias = ? AND id != ?", req.Alias, req.ID).Scan(&existingID); err == nil {
+		tx.Rollback()
+		sendJSONError(w, fmt.Sprintf("「%s」は別名として登録済みです（id=%d）", req.Alias, existingID), http.StatusConflict)
+		return
+	}
+
+	id := req.ID
+	var before map[string]interface{}
+	if r.Method == "PUT" {
+		if before, err = snapshotRow(tx, "item_aliases", id); err != nil {
+			tx.Rollback()
+			sendJSONError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if before == nil {
+			tx.Rollback()
+			sendJSONError(w, "not found", http.StatusNotFound)
+			return
+		}
+		if _, err := tx.Exec("UPDATE item_aliases SET alias = ?, catalog_id = ?, details = ? WHERE id = ?",
+			req.Alias, catalogID, req.Details, id); err != nil {
+			tx.Rollback()
+			sendJSONError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	} else {
+		res, err := tx.Exec("INSERT INTO item_aliases (alias, catalog_id, details) VALUES (?, ?, ?)", req.Alias, catalogID, req.Details)
+		if err != nil {
+			tx.Rollback()
+			sendJSONError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		newID, _ := res.LastInsertId()
+		id = int(newID)
+	}
+
+	after, err := snapshotRow(tx, "item_aliases", id)
+	if err == nil {
+		action := AuditCreate
+		if before != nil {
+			action = AuditUpdate
+		}
+		err = writeAudit(tx, r, AuditAlias, id, action, before, after)
+	}
+	if err != nil {
+		tx.Rollback()
+		sendJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		sendJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"status": "success", "id": id})
+}
+
+func deleteCatalogAlias(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.URL.Query().Get("id"))
+	if err != nil {
+		sendJSONError(w, "id required", http.StatusBadRequest)
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		sendJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	before, err := snapshotRow(tx, "item_aliases", id)
+	if err != nil {
+		tx.Rollback()
+		sendJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if before == nil {
+		tx.Rollback()
+		sendJSONError(w, "not found", http.StatusNotFound)
+		return
+	}
+	if _, err := tx.Exec("DELETE FROM item_aliases WHERE id = ?", id); err != nil {
+		tx.Rollback()
+		sendJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := writeAudit(tx, r, AuditAlias, id, AuditDelete, before, nil); err != nil {
+		tx.Rollback()
+		sendJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		sendJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "deleted"})
+}
+
+// 別名の付け先を id か名前で探す。見つからなければ sql.ErrNoRows
+func resolveAliasTarget(tx *sql.Tx, id int, name string) (int, error) {
+	switch {
+	case id != 0:
+		err := tx.QueryRow("SELECT id FROM item_catalog WHERE id = ?", id).Scan(&id)
+		return id, err
+	case strings.TrimSpace(name) != "":
+		err := tx.QueryRow("SELECT id FROM item_catalog WHERE name = ?", strings.TrimSpace(name)).Scan(&id)
+		return id, err
+	}
+	return 0, fmt.Errorf("catalog_id か catalog_name を指定してください")
+}
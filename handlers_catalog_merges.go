@@ -0,0 +1,335 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"kimichan/catalog"
+)
+
+// 統合時に catalog_id を付け替えるテーブル（tools/master_cleaner と共通）
+var catalogRefTables = catalog.RefTables
+
+type CatalogMerge struct {
+	ID             int                      `json:"id"`
+	SourceID       int                      `json:"source_id"`
+	TargetID       int                      `json:"target_id"`
+	SourceName     string                   `json:"source_name"`
+	TargetName     string                   `json:"target_name"`
+	SourceSnapshot map[string]interface{}   `json:"source_snapshot"`
+	MovedRows      map[string][]int         `json:"moved_rows"`
+	DeletedRows    map[string][]interface{} `json:"deleted_rows"`
+	MergedBy       string                   `json:"merged_by"`
+	CreatedAt      string                   `json:"created_at"`
+	RevertedAt     string                   `json:"reverted_at,omitempty"`
+	RevertedBy     string                   `json:"reverted_by,omitempty"`
+}
+
+// sourceID を targetID に統合し、catalog_merges に記録する。before は統合元の行のスナップショット
+func mergeCatalogItem(tx *sql.Tx, r *http.Request, sourceID, targetID int, before map[string]interface{}) (int, error) {
+	moved := make(map[string][]int)
+	deleted := make(map[string][]interface{})
+
+	for _, table := range catalogRefTables {
+		ids, err := selectIDs(tx, "SELECT id FROM "+table+" WHERE catalog_id = ?", sourceID)
+		if err != nil {
+			return 0, err
+		}
+		if _, err := tx.Exec("UPDATE "+table+" SET catalog_id = ? WHERE catalog_id = ?", targetID, sourceID); err != nil {
+			return 0, fmt.Errorf("%s: %w", table, err)
+		}
+		moved[table] = ids
+	}
+
+	// 調味料ストックは catalog_id ごとに1行なので、統合先に既にあれば統合元を捨てる
+	var targetHasSeasoning int
+	if err := tx.QueryRow("SELECT COUNT(*) FROM refrigerator_seasonings WHERE catalog_id = ?", targetID).Scan(&targetHasSeasoning); err != nil {
+		return 0, err
+	}
+	if targetHasSeasoning > 0 {
+		rows, err := snapshotRows(tx, "SELECT * FROM refrigerator_seasonings WHERE catalog_id = ?", sourceID)
+		if err != nil {
+			return 0, err
+		}
+		for _, row := range rows {
+			deleted["refrigerator_seasonings"] = append(deleted["refrigerator_seasonings"], row)
+		}
+		if _, err := tx.Exec("DELETE FROM refrigerator_seasonings WHERE catalog_id = ?", sourceID); err != nil {
+			return 0, err
+		}
+	} else {
+		ids, err := selectIDs(tx, "SELECT id FROM refrigerator_seasonings WHERE catalog_id = ?", sourceID)
+		if err != nil {
+			return 0, err
+		}
+		if _, err := tx.Exec("UPDATE refrigerator_seasonings SET catalog_id = ? WHERE catalog_id = ?", targetID, sourceID); err != nil {
+			return 0, err
+		}
+		moved["refrigerator_seasonings"] = ids
+	}
+
+	if _, err := tx.Exec("DELETE FROM item_catalog WHERE id = ?", sourceID); err != nil {
+		return 0, err
+	}
+
+	snapshotJSON, _ := json.Marshal(before)
+	movedJSON, _ := json.Marshal(moved)
+	deletedJSON, _ := json.Marshal(deleted)
+	actor := ""
+	if u := currentUser(r); u != nil {
+		actor = u.Username
+	}
+	res, err := tx.Exec("INSERT INTO catalog_merges(source_id, target_id, source_snapshot, moved_rows, deleted_rows, merged_by) VALUES(?, ?, ?, ?, ?, ?)",
+		sourceID, targetID, string(snapshotJSON), string(movedJSON), string(deletedJSON), actor)
+	if err != nil {
+		return 0, err
+	}
+	mergeID, _ := res.LastInsertId()
+
+	after := map[string]interface{}{"merged_into": targetID, "merge_id": mergeID, "moved_rows": moved}
+	if err := writeAudit(tx, r, AuditCatalog, sourceID, AuditMerge, before, after); err != nil {
+		return 0, err
+	}
+	return int(mergeID), nil
+}
+
+// GET  /api/catalog/merges           最近の統合一覧
+// POST /api/catalog/merges {"id": N} 統合を取り消す
+func handleCatalogMerges(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		getCatalogMerges(w, r)
+	case "POST":
+		revertCatalogMerge(w, r)
+	default:
+		sendJSONError(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func getCatalogMerges(w http.ResponseWriter, r *http.Request) {
+	limit := 50
+	if l, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && l > 0 && l <= 500 {
+		limit = l
+	}
+
+	rows, err := db.Query(`
+		SELECT m.id, m.source_id, m.target_id, m.source_snapshot, m.moved_rows, m.deleted_rows,
+			COALESCE(m.merged_by, ''), m.created_at, m.reverted_at, m.reverted_by, COALESCE(c.name, '')
+		FROM catalog_merges m
+		LEFT JOIN item_catalog c ON m.target_id = c.id
+		ORDER BY m.id DESC LIMIT ?`, limit)
+	if err != nil {
+		sendJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	merges := []CatalogMerge{}
+	for rows.Next() {
+		m, err := scanCatalogMerge(rows)
+		if err != nil {
+			sendJSONError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		merges = append(merges, *m)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(merges)
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanCatalogMerge(row rowScanner) (*CatalogMerge, error) {
+	var m CatalogMerge
+	var snapshot, moved, deleted string
+	var revertedAt, revertedBy sql.NullString
+	if err := row.Scan(&m.ID, &m.SourceID, &m.TargetID, &snapshot, &moved, &deleted,
+		&m.MergedBy, &m.CreatedAt, &revertedAt, &revertedBy, &m.TargetName); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(snapshot), &m.SourceSnapshot); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(moved), &m.MovedRows); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(deleted), &m.DeletedRows); err != nil {
+		return nil, err
+	}
+	if name, ok := m.SourceSnapshot["name"].(string); ok {
+		m.SourceName = name
+	}
+	m.RevertedAt = revertedAt.String
+	m.RevertedBy = revertedBy.String
+	return &m, nil
+}
+
+// 統合元のカタログ行を元の id で復元し、統合時に付け替えた行だけを元に戻す。
+// 統合後に追加された行は統合先に残る
+func revertCatalogMerge(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ID int `json:"id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.ID == 0 {
+		sendJSONError(w, "id required", http.StatusBadRequest)
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		sendJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	m, err := scanCatalogMerge(tx.QueryRow(`
+		SELECT m.id, m.source_id, m.target_id, m.source_snapshot, m.moved_rows, m.deleted_rows,
+			COALESCE(m.merged_by, ''), m.created_at, m.reverted_at, m.reverted_by, COALESCE(c.name, '')
+		FROM catalog_merges m
+		LEFT JOIN item_catalog c ON m.target_id = c.id
+		WHERE m.id = ?`, req.ID))
+	if err == sql.ErrNoRows {
+		tx.Rollback()
+		sendJSONError(w, "not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		tx.Rollback()
+		sendJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if m.RevertedAt != "" {
+		tx.Rollback()
+		sendJSONError(w, "この統合は既に取り消されています", http.StatusConflict)
+		return
+	}
+
+	var conflictID int
+	err = tx.QueryRow("SELECT id FROM item_catalog WHERE id = ? OR name = ?", m.SourceID, m.SourceName).Scan(&conflictID)
+	if err == nil {
+		tx.Rollback()
+		sendJSONError(w, fmt.Sprintf("「%s」(id=%d) が既に存在するため取り消せません", m.SourceName, conflictID), http.StatusConflict)
+		return
+	}
+
+	if err := restoreRow(tx, "item_catalog", m.SourceSnapshot); err != nil {
+		tx.Rollback()
+		sendJSONError(w, "カタログ復元失敗: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	restored := make(map[string]int)
+	for table, ids := range m.MovedRows {
+		if !isCatalogMergeTable(table) {
+			continue
+		}
+		for _, id := range ids {
+			// 統合後に別の項目へ付け替えられた行は触らない
+			res, err := tx.Exec("UPDATE "+table+" SET catalog_id = ? WHERE id = ? AND catalog_id = ?", m.SourceID, id, m.TargetID)
+			if err != nil {
+				tx.Rollback()
+				sendJSONError(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			n, _ := res.RowsAffected()
+			restored[table] += int(n)
+		}
+	}
+	for table, rows := range m.DeletedRows {
+		if !isCatalogMergeTable(table) {
+			continue
+		}
+		for _, row := range rows {
+			if rowMap, ok := row.(map[string]interface{}); ok {
+				if err := restoreRow(tx, table, rowMap); err != nil {
+					tx.Rollback()
+					sendJSONError(w, err.Error(), http.StatusInternalServerError)
+					return
+				}
+				restored[table]++
+			}
+		}
+	}
+
+	actor := ""
+	if u := currentUser(r); u != nil {
+		actor = u.Username
+	}
+	if _, err := tx.Exec("UPDATE catalog_merges SET reverted_at = CURRENT_TIMESTAMP, reverted_by = ? WHERE id = ?", actor, m.ID); err != nil {
+		tx.Rollback()
+		sendJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	after, err := snapshotRow(tx, "item_catalog", m.SourceID)
+	if err == nil {
+		err = writeAudit(tx, r, AuditCatalog, m.SourceID, AuditUnmerge, map[string]interface{}{"merged_into": m.TargetID, "merge_id": m.ID}, after)
+	}
+	if err != nil {
+		tx.Rollback()
+		sendJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		sendJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"status": "reverted", "source_id": m.SourceID, "restored_rows": restored})
+}
+
+func isCatalogMergeTable(table string) bool {
+	if table == "refrigerator_seasonings" {
+		return true
+	}
+	for _, t := range catalogRefTables {
+		if t == table {
+			return true
+		}
+	}
+	return false
+}
+
+// スナップショット（カラム名 → 値）をそのまま INSERT する。呼び出し側でテーブル名を検証すること
+func restoreRow(tx *sql.Tx, table string, row map[string]interface{}) error {
+	cols := make([]string, 0, len(row))
+	placeholders := make([]string, 0, len(row))
+	args := make([]interface{}, 0, len(row))
+	for c, v := range row {
+		if c == "ingredients" {
+			continue
+		}
+		cols = append(cols, `"`+c+`"`)
+		placeholders = append(placeholders, "?")
+		args = append(args, v)
+	}
+	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", table, strings.Join(cols, ", "), strings.Join(placeholders, ", "))
+	_, err := tx.Exec(query, args...)
+	return err
+}
+
+func selectIDs(tx *sql.Tx, query string, args ...interface{}) ([]int, error) {
+	rows, err := tx.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	ids := []int{}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"kimichan/notify"
+)
+
+// 画面（inventory_edit.js）が送ってくる形式に揃えて保存する
+const expirationLayout = "2006-01-02T00:00:00Z"
+
+// 賞味期限を検証して "YYYY-MM-DDT00:00:00Z" に正規化する（空欄は期限なし）
+func normalizeExpirationDate(s string) (string, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return "", nil
+	}
+	for _, layout := range []string{"2006-01-02", time.RFC3339, "2006-01-02T15:04:05"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t.Format(expirationLayout), nil
+		}
+	}
+	return "", fmt.Errorf("賞味期限は YYYY-MM-DD 形式で指定してください: %s", s)
+}
+
+type ExpiringItem struct {
+	Ingredient
+	DaysLeft int `json:"days_left"`
+}
+
+type ExpiringGroup struct {
+	LocationID int            `json:"location_id"`
+	Location   string         `json:"location"`
+	Items      []ExpiringItem `json:"items"`
+}
+
+// days 日後までに期限が来る在庫（期限切れを含む）を場所ごとにまとめる
+func loadExpiring(days int, now time.Time) ([]ExpiringGroup, error) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	cutoff := today.AddDate(0, 0, days).Format("2006-01-02")
+
+	rows, err := db.Query(`
+		SELECT i.id, i.catalog_id, i.amount, i.unit, i.expiration_date, i.location_id, l.name,
+			c.name, COALESCE(c.kana, '')
+		FROM refrigerator_ingredients i
+		JOIN item_catalog c ON i.catalog_id = c.id
+		JOIN locations l ON i.location_id = l.id
+		WHERE i.expiration_date GLOB '[0-9][0-9][0-9][0-9]-[0-9][0-9]-[0-9][0-9]*'
+		  AND substr(i.expiration_date, 1, 10) <= ?
+		ORDER BY l.priority ASC, substr(i.expiration_date, 1, 10) ASC, c.name ASC`, cutoff)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	groups := []ExpiringGroup{}
+	for rows.Next() {
+		var item ExpiringItem
+		if err := rows.Scan(&item.ID, &item.CatalogID, &item.Amount, &item.Unit, &item.ExpirationDate,
+			&item.LocationID, &item.Location, &item.Name, &item.Kana); err != nil {
+			return nil, err
+		}
+		if exp, err := time.Parse("2006-01-02", item.ExpirationDate[:10]); err == nil {
+			item.DaysLeft = int(exp.Sub(today).Hours() / 24)
+		}
+		if n := len(groups); n == 0 || groups[n-1].LocationID != item.LocationID {
+			groups = append(groups, ExpiringGroup{LocationID: item.LocationID, Location: item.Location})
+		}
+		g := &groups[len(groups)-1]
+		g.Items = append(g.Items, item)
+	}
+	return groups, rows.Err()
+}
+
+// GET  /api/ingredients/expiring?days=3  期限が近い在庫を場所ごとに返す
+// POST /api/ingredients/expiring?days=3  同じ内容をすぐに通知する（通知先の動作確認用）
+func handleExpiringIngredients(w http.ResponseWriter, r *http.Request) {
+	days := expiryDays
+	if s := r.URL.Query().Get("days"); s != "" {
+		d, err := strconv.Atoi(s)
+		if err != nil || d < 0 || d > 365 {
+			sendJSONError(w, "days は 0〜365 の数値で指定してください", http.StatusBadRequest)
+			return
+		}
+		days = d
+	}
+
+	switch r.Method {
+	case "GET":
+		groups, err := loadExpiring(days, time.Now())
+		if err != nil {
+			sendJSONError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(groups)
+	case "POST":
+		count, err := runExpiryScan(r.Context(), days, time.Now())
+		if err != nil {
+			sendJSONError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{"status": "notified", "count": count})
+	default:
+		sendJSONError(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// 期限アラートの設定（startExpiryScanner で環境変数から読み込む）
+var (
+	expiryNotifier notify.Notifier = notify.LogNotifier{}
+	expiryDays                     = 3
+	expiryHour                     = 8
+)
+
+// 期限が近い在庫を集めて通知する。対象が無ければ通知しない
+func runExpiryScan(ctx context.Context, days int, now time.Time) (int, error) {
+	groups, err := loadExpiring(days, now)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	var body strings.Builder
+	for _, g := range groups {
+		fmt.Fprintf(&body, "■ %s\n", g.Location)
+		for _, item := range g.Items {
+			count++
+			switch {
+			case item.DaysLeft < 0:
+				fmt.Fprintf(&body, "  %s（%d日前に期限切れ）\n", item.Name, -item.DaysLeft)
+			case item.DaysLeft == 0:
+				fmt.Fprintf(&body, "  %s（今日まで）\n", item.Name)
+			default:
+				fmt.Fprintf(&body, "  %s（あと%d日）\n", item.Name, item.DaysLeft)
+			}
+		}
+	}
+	if count == 0 {
+		return 0, nil
+	}
+
+	msg := notify.Message{
+		Subject: fmt.Sprintf("賞味期限が近い食材が%d件あります", count),
+		Body:    body.String(),
+		Data:    groups,
+	}
+	return count, expiryNotifier.Notify(ctx, msg)
+}
+
+// 毎日 expiryHour 時に期限チェックを行うゴルーチンを起動する
+//
+//	KIMICHAN_EXPIRY_DAYS  何日先までを対象にするか（既定 3）
+//	KIMICHAN_EXPIRY_HOUR  チェックする時刻（0〜23、既定 8）
+//
+// 通知先は notify.FromEnv を参照
+func startExpiryScanner() error {
+	n, err := notify.FromEnv()
+	if err != nil {
+		return err
+	}
+	expiryNotifier = n
+	if s := os.Getenv("KIMICHAN_EXPIRY_DAYS"); s != "" {
+		if expiryDays, err = strconv.Atoi(s); err != nil || expiryDays < 0 {
+			return fmt.Errorf("KIMICHAN_EXPIRY_DAYS が不正です: %s", s)
+		}
+	}
+	if s := os.Getenv("KIMICHAN_EXPIRY_HOUR"); s != "" {
+		if expiryHour, err = strconv.Atoi(s); err != nil || expiryHour < 0 || expiryHour > 23 {
+			return fmt.Errorf("KIMICHAN_EXPIRY_HOUR が不正です: %s", s)
+		}
+	}
+
+	go func() {
+		for {
+			now := time.Now()
+			next := time.Date(now.Year(), now.Month(), now.Day(), expiryHour, 0, 0, 0, now.Location())
+			if !next.After(now) {
+				next = next.AddDate(0, 0, 1)
+			}
+			time.Sleep(time.Until(next))
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+			if _, err := runExpiryScan(ctx, expiryDays, time.Now()); err != nil {
+				log.Printf("expiry scan failed: %v", err)
+			}
+			cancel()
+		}
+	}()
+	return nil
+}
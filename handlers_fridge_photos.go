@@ -21,8 +21,8 @@ func handleFridgePhotos(w http.ResponseWriter, r *http.Request) {
 }
 
 func getFridgePhotos(w http.ResponseWriter, _ *http.Request) {
-	// location も取得するように変更
-	rows, err := db.Query("SELECT id, image_path, location, created_at FROM fridge_photos ORDER BY id DESC")
+	// 場所名は locations から引く
+	rows, err := db.Query("SELECT p.id, p.image_path, p.location_id, l.name, p.created_at FROM fridge_photos p LEFT JOIN locations l ON p.location_id = l.id ORDER BY p.id DESC")
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -32,12 +32,13 @@ func getFridgePhotos(w http.ResponseWriter, _ *http.Request) {
 	photos := []FridgePhoto{}
 	for rows.Next() {
 		var p FridgePhoto
+		var locID sql.NullInt64
 		var loc sql.NullString
-		// location をスキャン
-		if err := rows.Scan(&p.ID, &p.ImagePath, &loc, &p.CreatedAt); err != nil {
+		if err := rows.Scan(&p.ID, &p.ImagePath, &locID, &loc, &p.CreatedAt); err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
+		p.LocationID = int(locID.Int64)
 		p.Location = loc.String
 		photos = append(photos, p)
 	}
@@ -55,13 +56,15 @@ func addFridgePhoto(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "image_path required", http.StatusBadRequest)
 		return
 	}
-	// 場所が指定されていなければ「その他」とする
-	if p.Location == "" {
-		p.Location = "その他"
+	// 場所は id・名前のどちらでも受け付ける（未指定なら「その他」）
+	locID, locName, err := resolveLocation(db, p.LocationID, p.Location)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
+	p.LocationID, p.Location = locID, locName
 
-	// location をDBに保存
-	res, err := db.Exec("INSERT INTO fridge_photos(image_path, location) VALUES(?, ?)", p.ImagePath, p.Location)
+	res, err := db.Exec("INSERT INTO fridge_photos(image_path, location_id) VALUES(?, ?)", p.ImagePath, p.LocationID)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -103,6 +103,7 @@ func handleCatalogImport(w http.ResponseWriter, r *http.Request) {
 			category = ""
 		}
 
+		// 同じ名前だけを重複とみなす（よみがなや別名が同じでも 鮭 と 酒 のように別の項目がある）
 		var existingID int
 		err = checkStmt.QueryRow(name).Scan(&existingID)
 		if err == nil {
@@ -115,6 +116,11 @@ func handleCatalogImport(w http.ResponseWriter, r *http.Request) {
 			result.Errors = append(result.Errors, name+": "+err.Error())
 			continue
 		}
+		if err := auditCatalogUpsert(tx, r, name, nil); err != nil {
+			tx.Rollback()
+			http.Error(w, "監査ログの保存に失敗しました: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
 		result.Added++
 	}
 
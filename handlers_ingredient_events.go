@@ -0,0 +1,305 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strconv"
+)
+
+// 在庫イベントの種類
+const (
+	EventAdded     = "added"
+	EventConsumed  = "consumed"
+	EventDiscarded = "discarded"
+	EventMoved     = "moved"
+)
+
+// 在庫を削除するときの理由（consumed 以外は廃棄として集計する）
+const (
+	ReasonConsumed = "consumed"
+	ReasonExpired  = "expired"
+	ReasonSpoiled  = "spoiled"
+	ReasonOther    = "other"
+)
+
+func isValidDeleteReason(reason string) bool {
+	switch reason {
+	case ReasonConsumed, ReasonExpired, ReasonSpoiled, ReasonOther:
+		return true
+	}
+	return false
+}
+
+type IngredientEvent struct {
+	ID             int     `json:"id"`
+	IngredientID   int     `json:"ingredient_id"`
+	CatalogID      int     `json:"catalog_id"`
+	EventType      string  `json:"event_type"`
+	Amount         float64 `json:"amount"`
+	Unit           string  `json:"unit"`
+	LocationID     int     `json:"location_id,omitempty"`
+	Location       string  `json:"location,omitempty"`
+	FromLocationID int     `json:"from_location_id,omitempty"`
+	Reason         string  `json:"reason,omitempty"`
+	ExpirationDate string  `json:"expiration_date,omitempty"`
+	Note           string  `json:"note,omitempty"`
+	Actor          string  `json:"actor"`
+	CreatedAt      string  `json:"created_at"`
+}
+
+// 在庫の変更と同じトランザクションでイベントを記録する
+func recordIngredientEvent(tx *sql.Tx, r *http.Request, ev IngredientEvent) error {
+	actor := "system"
+	if u := currentUser(r); u != nil {
+		actor = u.Username
+	}
+	_, err := tx.Exec(`INSERT INTO ingredient_events(ingredient_id, catalog_id, event_type, amount, unit, location_id, from_location_id, reason, expiration_date, note, actor)
+		VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		ev.IngredientID, ev.CatalogID, ev.EventType, ev.Amount, ev.Unit,
+		nullableID(ev.LocationID), nullableID(ev.FromLocationID), ev.Reason, ev.ExpirationDate, ev.Note, actor)
+	return err
+}
+
+func nullableID(id int) interface{} {
+	if id == 0 {
+		return nil
+	}
+	return id
+}
+
+// 在庫行のスナップショットからイベントを組み立てる（amount は行の残量）
+func eventFromSnapshot(id int, eventType string, row map[string]interface{}) IngredientEvent {
+	ev := IngredientEvent{IngredientID: id, EventType: eventType}
+	ev.CatalogID = int(snapshotInt(row["catalog_id"]))
+	ev.LocationID = int(snapshotInt(row["location_id"]))
+	ev.Amount = snapshotFloat(row["amount"])
+	if u, ok := row["unit"].(string); ok {
+		ev.Unit = u
+	}
+	if d, ok := row["expiration_date"].(string); ok {
+		ev.ExpirationDate = d
+	}
+	return ev
+}
+
+// 場所が変わっていれば moved イベントを記録する
+func recordMoveEvent(tx *sql.Tx, r *http.Request, before, after map[string]interface{}) error {
+	from := int(snapshotInt(before["location_id"]))
+	to := int(snapshotInt(after["location_id"]))
+	if from == to {
+		return nil
+	}
+	ev := eventFromSnapshot(int(snapshotInt(after["id"])), EventMoved, after)
+	ev.FromLocationID = from
+	return recordIngredientEvent(tx, r, ev)
+}
+
+func snapshotInt(v interface{}) int64 {
+	switch n := v.(type) {
+	case int64:
+		return n
+	case float64:
+		return int64(n)
+	}
+	return 0
+}
+
+func snapshotFloat(v interface{}) float64 {
+	switch n := v.(type) {
+	case int64:
+		return float64(n)
+	case float64:
+		return n
+	}
+	return 0
+}
+
+// POST /api/ingredients/consume {"id": 3, "amount": 1.5, "note": "..."}
+// amount を減らし、0 以下になったら在庫から削除する
+func handleConsumeIngredient(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		sendJSONError(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req struct {
+		ID     int     `json:"id"`
+		Amount float64 `json:"amount"`
+		Note   string  `json:"note"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.ID == 0 {
+		sendJSONError(w, "id required", http.StatusBadRequest)
+		return
+	}
+	if req.Amount <= 0 {
+		sendJSONError(w, "amount must be positive", http.StatusBadRequest)
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		sendJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	result, err := consumeIngredient(tx, r, req.ID, req.Amount, req.Note)
+	if err == sql.ErrNoRows {
+		tx.Rollback()
+		sendJSONError(w, "not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		tx.Rollback()
+		sendJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		sendJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+type ConsumeResult struct {
+	ID        int     `json:"id"`
+	Consumed  float64 `json:"consumed"`
+	Remaining float64 `json:"remaining"`
+	Unit      string  `json:"unit"`
+	Deleted   bool    `json:"deleted"`
+}
+
+// 在庫1行から amount を使う。在庫より多い場合は残り全部を使ったことにする
+func consumeIngredient(tx *sql.Tx, r *http.Request, id int, amount float64, note string) (*ConsumeResult, error) {
+	before, err := snapshotRow(tx, "refrigerator_ingredients", id)
+	if err != nil {
+		return nil, err
+	}
+	if before == nil {
+		return nil, sql.ErrNoRows
+	}
+
+	ev := eventFromSnapshot(id, EventConsumed, before)
+	ev.Note = note
+	current := ev.Amount
+	if current < 0 {
+		// 量不明（-1）の在庫は使った時点で空にする
+		current = amount
+	}
+	ev.Amount = amount
+	if ev.Amount > current {
+		ev.Amount = current
+	}
+	result := &ConsumeResult{ID: id, Consumed: ev.Amount, Remaining: current - ev.Amount, Unit: ev.Unit}
+
+	var after map[string]interface{}
+	if result.Remaining <= 0 {
+		result.Remaining = 0
+		result.Deleted = true
+		if _, err := tx.Exec("DELETE FROM refrigerator_ingredients WHERE id = ?", id); err != nil {
+			return nil, err
+		}
+	} else {
+		if _, err := tx.Exec("UPDATE refrigerator_ingredients SET amount = ?, updated_at = datetime('now','localtime') WHERE id = ?", result.Remaining, id); err != nil {
+			return nil, err
+		}
+		if after, err = snapshotRow(tx, "refrigerator_ingredients", id); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := recordIngredientEvent(tx, r, ev); err != nil {
+		return nil, err
+	}
+
+	action := AuditUpdate
+	if result.Deleted {
+		action = AuditDelete
+	}
+	if err := writeAudit(tx, r, AuditIngredient, id, action, before, after); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// GET /api/ingredients/history?catalog_id=1
+// カタログ項目ごとのイベント一覧と、月別・単位別の集計を返す
+func handleIngredientHistory(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		sendJSONError(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	catalogID, err := strconv.Atoi(r.URL.Query().Get("catalog_id"))
+	if err != nil || catalogID == 0 {
+		sendJSONError(w, "catalog_id required", http.StatusBadRequest)
+		return
+	}
+
+	rows, err := db.Query(`
+		SELECT e.id, e.ingredient_id, e.catalog_id, e.event_type, e.amount, COALESCE(e.unit, ''),
+			COALESCE(e.location_id, 0), COALESCE(l.name, ''), COALESCE(e.from_location_id, 0),
+			COALESCE(e.reason, ''), COALESCE(e.expiration_date, ''), COALESCE(e.note, ''), COALESCE(e.actor, ''), e.created_at
+		FROM ingredient_events e
+		LEFT JOIN locations l ON e.location_id = l.id
+		WHERE e.catalog_id = ?
+		ORDER BY e.created_at DESC, e.id DESC`, catalogID)
+	if err != nil {
+		sendJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	events := []IngredientEvent{}
+	for rows.Next() {
+		var e IngredientEvent
+		if err := rows.Scan(&e.ID, &e.IngredientID, &e.CatalogID, &e.EventType, &e.Amount, &e.Unit,
+			&e.LocationID, &e.Location, &e.FromLocationID, &e.Reason, &e.ExpirationDate, &e.Note, &e.Actor, &e.CreatedAt); err != nil {
+			sendJSONError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		events = append(events, e)
+	}
+
+	type MonthlyUsage struct {
+		Month     string  `json:"month"`
+		Unit      string  `json:"unit"`
+		Added     float64 `json:"added"`
+		Consumed  float64 `json:"consumed"`
+		Discarded float64 `json:"discarded"`
+	}
+	sumRows, err := db.Query(`
+		SELECT strftime('%Y-%m', created_at) AS month, COALESCE(unit, ''),
+			SUM(CASE WHEN event_type = ? THEN MAX(amount, 0) ELSE 0 END),
+			SUM(CASE WHEN event_type = ? THEN MAX(amount, 0) ELSE 0 END),
+			SUM(CASE WHEN event_type = ? THEN MAX(amount, 0) ELSE 0 END)
+		FROM ingredient_events
+		WHERE catalog_id = ?
+		GROUP BY month, COALESCE(unit, '')
+		ORDER BY month ASC`, EventAdded, EventConsumed, EventDiscarded, catalogID)
+	if err != nil {
+		sendJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer sumRows.Close()
+
+	monthly := []MonthlyUsage{}
+	for sumRows.Next() {
+		var m MonthlyUsage
+		if err := sumRows.Scan(&m.Month, &m.Unit, &m.Added, &m.Consumed, &m.Discarded); err != nil {
+			sendJSONError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		monthly = append(monthly, m)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"catalog_id": catalogID,
+		"events":     events,
+		"monthly":    monthly,
+	})
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"net/http"
 	"os"
+	"time"
 )
 
 func handleIngredients(w http.ResponseWriter, r *http.Request) {
@@ -29,12 +30,13 @@ func getIngredients(w http.ResponseWriter, r *http.Request) {
 	// ★修正: c.kana も取得する
 	query := `
 		SELECT 
-			i.id, i.catalog_id, i.amount, i.unit, i.expiration_date, i.location, i.created_at, i.updated_at,
+			i.id, i.catalog_id, i.amount, i.unit, i.expiration_date, i.location_id, l.name, i.created_at, i.updated_at,
 			c.name, c.kana,
-			(SELECT COUNT(*) FROM recipe_ingredients ri WHERE ri.catalog_id = c.id) as recipe_count
+			(SELECT COUNT(*) FROM recipe_ingredients ri JOIN recipes r ON ri.recipe_id = r.id WHERE ri.catalog_id = c.id AND r.deleted_at IS NULL) as recipe_count
 		FROM refrigerator_ingredients i
 		JOIN item_catalog c ON i.catalog_id = c.id
-		ORDER BY i.location ASC, c.name ASC
+		JOIN locations l ON i.location_id = l.id
+		ORDER BY l.priority ASC, c.name ASC
 	`
 
 	if isCloud := os.Getenv("K_SERVICE") != ""; isCloud && !isAll {
@@ -53,7 +55,7 @@ func getIngredients(w http.ResponseWriter, r *http.Request) {
 		var item Ingredient
 		var kana sql.NullString // カナは空の可能性があるのでNullStringで受ける
 		if err := rows.Scan(
-			&item.ID, &item.CatalogID, &item.Amount, &item.Unit, &item.ExpirationDate, &item.Location, &item.CreatedAt, &item.UpdatedAt,
+			&item.ID, &item.CatalogID, &item.Amount, &item.Unit, &item.ExpirationDate, &item.LocationID, &item.Location, &item.CreatedAt, &item.UpdatedAt,
 			&item.Name, &kana, &item.RecipeCount,
 		); err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -75,30 +77,75 @@ func addIngredient(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "catalog_id required", http.StatusBadRequest)
 		return
 	}
-	if item.Location == "" {
-		item.Location = "その他"
+	expirationDate, err := normalizeExpirationDate(item.ExpirationDate)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
+	item.ExpirationDate = expirationDate
 
-	stmt, err := db.Prepare("INSERT INTO refrigerator_ingredients(catalog_id, amount, unit, expiration_date, location) VALUES(?, ?, ?, ?, ?)")
+	tx, err := db.Begin()
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	defer stmt.Close()
 
-	res, err := stmt.Exec(item.CatalogID, item.Amount, item.Unit, item.ExpirationDate, item.Location)
+	// 場所は id・名前のどちらでも受け付ける（未指定なら「その他」）
+	locID, locName, err := resolveLocation(tx, item.LocationID, item.Location)
 	if err != nil {
+		tx.Rollback()
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	item.LocationID, item.Location = locID, locName
+
+	if err := insertIngredient(tx, r, &item); err != nil {
+		tx.Rollback()
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := tx.Commit(); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	id, _ := res.LastInsertId()
-	item.ID = int(id)
 
 	w.WriteHeader(http.StatusCreated)
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(item)
 }
 
+// 在庫を1行追加して監査ログと added イベントを書く（item.LocationID は解決済みであること）
+// 賞味期限が未入力なら、場所の保存方法と日持ち日数から自動で入れる
+func insertIngredient(tx *sql.Tx, r *http.Request, item *Ingredient) error {
+	if err := fillExpirationDate(tx, item, time.Now()); err != nil {
+		return err
+	}
+
+	res, err := tx.Exec("INSERT INTO refrigerator_ingredients(catalog_id, amount, unit, expiration_date, location_id) VALUES(?, ?, ?, ?, ?)",
+		item.CatalogID, item.Amount, item.Unit, item.ExpirationDate, item.LocationID)
+	if err != nil {
+		return err
+	}
+	id, _ := res.LastInsertId()
+	item.ID = int(id)
+
+	after, err := snapshotRow(tx, "refrigerator_ingredients", item.ID)
+	if err != nil {
+		return err
+	}
+	if err := writeAudit(tx, r, AuditIngredient, item.ID, AuditCreate, nil, after); err != nil {
+		return err
+	}
+	return recordIngredientEvent(tx, r, IngredientEvent{
+		IngredientID: item.ID,
+		CatalogID:    item.CatalogID,
+		EventType:    EventAdded,
+		Amount:       item.Amount,
+		Unit:         item.Unit,
+		LocationID:   item.LocationID,
+	})
+}
+
 func updateIngredient(w http.ResponseWriter, r *http.Request) {
 	var item Ingredient
 	if err := json.NewDecoder(r.Body).Decode(&item); err != nil {
@@ -109,9 +156,58 @@ func updateIngredient(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "id required", http.StatusBadRequest)
 		return
 	}
+	expirationDate, err := normalizeExpirationDate(item.ExpirationDate)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	item.ExpirationDate = expirationDate
+
+	tx, err := db.Begin()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	locID, _, err := resolveLocation(tx, item.LocationID, item.Location)
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	before, err := snapshotRow(tx, "refrigerator_ingredients", item.ID)
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if before == nil {
+		tx.Rollback()
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+
+	_, err = tx.Exec("UPDATE refrigerator_ingredients SET amount=?, expiration_date=?, location_id=?, updated_at=datetime('now','localtime') WHERE id=?", item.Amount, item.ExpirationDate, locID, item.ID)
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
 
-	_, err := db.Exec("UPDATE refrigerator_ingredients SET amount=?, expiration_date=?, location=?, updated_at=datetime('now','localtime') WHERE id=?", item.Amount, item.ExpirationDate, item.Location, item.ID)
+	after, err := snapshotRow(tx, "refrigerator_ingredients", item.ID)
+	if err == nil {
+		err = writeAudit(tx, r, AuditIngredient, item.ID, AuditUpdate, before, after)
+	}
+	if err == nil {
+		err = recordMoveEvent(tx, r, before, after)
+	}
 	if err != nil {
+		tx.Rollback()
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := tx.Commit(); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -130,12 +226,58 @@ func deleteIngredient(w http.ResponseWriter, r *http.Request) {
 	var id int
 	fmt.Sscanf(idStr, "%d", &id)
 
-	_, err := db.Exec("DELETE FROM refrigerator_ingredients WHERE id=?", id)
+	// 削除理由（食べきった / 期限切れ / 傷んだ / その他）。省略時は other
+	reason := r.URL.Query().Get("reason")
+	if reason == "" {
+		reason = ReasonOther
+	}
+	if !isValidDeleteReason(reason) {
+		http.Error(w, "invalid reason: "+reason, http.StatusBadRequest)
+		return
+	}
+
+	tx, err := db.Begin()
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
+	before, err := snapshotRow(tx, "refrigerator_ingredients", id)
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	_, err = tx.Exec("DELETE FROM refrigerator_ingredients WHERE id=?", id)
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if before != nil {
+		err := writeAudit(tx, r, AuditIngredient, id, AuditDelete, before, nil)
+		if err == nil {
+			eventType := EventDiscarded
+			if reason == ReasonConsumed {
+				eventType = EventConsumed
+			}
+			ev := eventFromSnapshot(id, eventType, before)
+			ev.Reason = reason
+			err = recordIngredientEvent(tx, r, ev)
+		}
+		if err != nil {
+			tx.Rollback()
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(map[string]string{"status": "deleted"})
 }
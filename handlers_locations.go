@@ -1,11 +1,59 @@
 package main
 
 import (
+	"bytes"
+	"database/sql"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
+	"strconv"
 )
 
+// 場所未指定のときに使う場所
+const defaultLocationName = "その他"
+
+// *sql.DB と *sql.Tx のどちらからでも引けるようにするためのインターフェース
+type queryRower interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// 書き込み時の場所指定（id または名前）を locations の行に解決する。
+// どちらも空なら「その他」。見つからない場合はエラーを返す
+func resolveLocation(q queryRower, id int, name string) (int, string, error) {
+	var err error
+	switch {
+	case id != 0:
+		err = q.QueryRow("SELECT id, name FROM locations WHERE id = ?", id).Scan(&id, &name)
+	case name != "":
+		err = q.QueryRow("SELECT id, name FROM locations WHERE name = ?", name).Scan(&id, &name)
+	default:
+		err = q.QueryRow("SELECT id, name FROM locations WHERE name = ?", defaultLocationName).Scan(&id, &name)
+	}
+	if err == sql.ErrNoRows {
+		return 0, "", fmt.Errorf("場所が見つかりません: %s", locationLabel(id, name))
+	}
+	return id, name, err
+}
+
+// "3" のような数字なら id、それ以外は名前として場所を解決する
+func resolveLocationParam(q queryRower, s string) (int, string, error) {
+	if id, err := strconv.Atoi(s); err == nil {
+		return resolveLocation(q, id, "")
+	}
+	return resolveLocation(q, 0, s)
+}
+
+func locationLabel(id int, name string) string {
+	if name != "" {
+		return name
+	}
+	if id != 0 {
+		return fmt.Sprintf("id=%d", id)
+	}
+	return defaultLocationName
+}
+
 // ★削除: type Location struct ... の定義をここから消去します
 // （models.go に定義済みのため）
 
@@ -16,7 +64,18 @@ func handleLocations(w http.ResponseWriter, r *http.Request) {
 	case "POST":
 		addLocation(w, r)
 	case "PUT":
-		reorderLocations(w, r)
+		// 配列なら並び替え、単体オブジェクトなら名前変更
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		if trimmed := bytes.TrimSpace(body); len(trimmed) > 0 && trimmed[0] == '{' {
+			renameLocation(w, r)
+		} else {
+			reorderLocations(w, r)
+		}
 	case "DELETE":
 		deleteLocation(w, r)
 	default:
@@ -25,7 +84,7 @@ func handleLocations(w http.ResponseWriter, r *http.Request) {
 }
 
 func getLocations(w http.ResponseWriter, _ *http.Request) {
-	rows, err := db.Query("SELECT id, name, priority FROM locations ORDER BY priority ASC")
+	rows, err := db.Query("SELECT id, name, priority, storage_type FROM locations ORDER BY priority ASC")
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -35,7 +94,7 @@ func getLocations(w http.ResponseWriter, _ *http.Request) {
 	locations := []Location{}
 	for rows.Next() {
 		var l Location
-		if err := rows.Scan(&l.ID, &l.Name, &l.Priority); err != nil {
+		if err := rows.Scan(&l.ID, &l.Name, &l.Priority, &l.StorageType); err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
@@ -55,11 +114,24 @@ func addLocation(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "name required", http.StatusBadRequest)
 		return
 	}
+	if l.StorageType == "" {
+		l.StorageType = StorageFridge
+	}
+	if !isValidStorageType(l.StorageType) {
+		http.Error(w, "storage_type は fridge / freezer / room のいずれかです", http.StatusBadRequest)
+		return
+	}
+
+	var existingID int
+	if err := db.QueryRow("SELECT id FROM locations WHERE name = ?", l.Name).Scan(&existingID); err == nil {
+		http.Error(w, fmt.Sprintf("「%s」は既に存在します", l.Name), http.StatusConflict)
+		return
+	}
 
 	var maxPriority int
 	db.QueryRow("SELECT COALESCE(MAX(priority), 0) FROM locations").Scan(&maxPriority)
 
-	res, err := db.Exec("INSERT INTO locations(name, priority) VALUES(?, ?)", l.Name, maxPriority+1)
+	res, err := db.Exec("INSERT INTO locations(name, priority, storage_type) VALUES(?, ?, ?)", l.Name, maxPriority+1, l.StorageType)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -72,6 +144,71 @@ func addLocation(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(l)
 }
 
+// 名前・保存方法の変更（在庫・写真は location_id で参照しているので場所の行だけ更新すればよい）
+// name / storage_type は省略すると今の値のまま
+func renameLocation(w http.ResponseWriter, r *http.Request) {
+	var req Location
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.ID == 0 || (req.Name == "" && req.StorageType == "") {
+		http.Error(w, "id and name or storage_type required", http.StatusBadRequest)
+		return
+	}
+	if req.StorageType != "" && !isValidStorageType(req.StorageType) {
+		http.Error(w, "storage_type は fridge / freezer / room のいずれかです", http.StatusBadRequest)
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	var l Location
+	err = tx.QueryRow("SELECT id, name, priority, storage_type FROM locations WHERE id = ?", req.ID).Scan(&l.ID, &l.Name, &l.Priority, &l.StorageType)
+	if err == sql.ErrNoRows {
+		tx.Rollback()
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		tx.Rollback()
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if req.Name != "" {
+		l.Name = req.Name
+	}
+	if req.StorageType != "" {
+		l.StorageType = req.StorageType
+	}
+
+	var dupID int
+	if err := tx.QueryRow("SELECT id FROM locations WHERE name = ? AND id != ?", l.Name, l.ID).Scan(&dupID); err == nil {
+		tx.Rollback()
+		http.Error(w, fmt.Sprintf("「%s」は既に存在します", l.Name), http.StatusConflict)
+		return
+	}
+
+	if _, err := tx.Exec("UPDATE locations SET name = ?, storage_type = ? WHERE id = ?", l.Name, l.StorageType, l.ID); err != nil {
+		tx.Rollback()
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(l)
+}
+
+// 使用中の場所は削除を拒否する。?reassign_to=<id または名前> を付けると、
+// 在庫・写真をその場所へ移してから削除する
 func deleteLocation(w http.ResponseWriter, r *http.Request) {
 	idStr := r.URL.Query().Get("id")
 	if idStr == "" {
@@ -81,11 +218,80 @@ func deleteLocation(w http.ResponseWriter, r *http.Request) {
 	var id int
 	fmt.Sscanf(idStr, "%d", &id)
 
-	_, err := db.Exec("DELETE FROM locations WHERE id = ?", id)
+	tx, err := db.Begin()
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+
+	var name string
+	err = tx.QueryRow("SELECT name FROM locations WHERE id = ?", id).Scan(&name)
+	if err == sql.ErrNoRows {
+		tx.Rollback()
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		tx.Rollback()
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	var ingCount, photoCount int
+	tx.QueryRow("SELECT COUNT(*) FROM refrigerator_ingredients WHERE location_id = ?", id).Scan(&ingCount)
+	tx.QueryRow("SELECT COUNT(*) FROM fridge_photos WHERE location_id = ?", id).Scan(&photoCount)
+
+	if ingCount > 0 || photoCount > 0 {
+		reassignStr := r.URL.Query().Get("reassign_to")
+		if reassignStr == "" {
+			tx.Rollback()
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusConflict)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"error_code":       "location_in_use",
+				"message":          fmt.Sprintf("「%s」には在庫%d件・写真%d件があります。移動先を指定してください", name, ingCount, photoCount),
+				"ingredient_count": ingCount,
+				"photo_count":      photoCount,
+			})
+			return
+		}
+
+		reassignID, _, err := resolveLocationParam(tx, reassignStr)
+		if err != nil || reassignID == id {
+			tx.Rollback()
+			http.Error(w, "移動先の場所が見つかりません", http.StatusBadRequest)
+			return
+		}
+
+		if _, err := tx.Exec("UPDATE refrigerator_ingredients SET location_id = ? WHERE location_id = ?", reassignID, id); err != nil {
+			tx.Rollback()
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if _, err := tx.Exec("UPDATE fridge_photos SET location_id = ? WHERE location_id = ?", reassignID, id); err != nil {
+			tx.Rollback()
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	// 在庫の履歴は消さずに場所だけ外す（外部キー制約があるため）
+	for _, col := range []string{"location_id", "from_location_id"} {
+		if _, err := tx.Exec("UPDATE ingredient_events SET "+col+" = NULL WHERE "+col+" = ?", id); err != nil {
+			tx.Rollback()
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if _, err := tx.Exec("DELETE FROM locations WHERE id = ?", id); err != nil {
+		tx.Rollback()
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(map[string]string{"status": "deleted"})
 }
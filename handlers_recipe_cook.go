@@ -0,0 +1,369 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"kimichan/quantity"
+	"kimichan/units"
+)
+
+// 材料を減らさなかった理由
+const (
+	CookSkipSeasoning     = "seasoning"       // 調味料はストック状態で管理しているので減らさない
+	CookSkipNoQuantity    = "no_quantity"     // 「適量」など数値の無い分量
+	CookSkipUnknownAmount = "unknown_amount"  // 在庫の量が不明（amount = -1）
+	CookSkipNotConvert    = "not_convertible" // 在庫の単位をレシピの単位に換算できない
+)
+
+// servings を指定したが、レシピの yield から人数を読めず倍率を決められない
+var errCookServingsUnknown = errors.New("レシピの人数（yield）を読み取れないため人数を変えられません")
+
+// 在庫1行ぶんの減らし方。量は在庫側の単位
+type CookDecrement struct {
+	IngredientID   int     `json:"ingredient_id"`
+	CatalogID      int     `json:"catalog_id"`
+	Name           string  `json:"name"`
+	Location       string  `json:"location"`
+	ExpirationDate string  `json:"expiration_date"`
+	Amount         float64 `json:"amount"`
+	Unit           string  `json:"unit"`
+	Before         float64 `json:"before"`
+	After          float64 `json:"after"` // 0 なら行ごと削除する
+}
+
+// レシピの材料1行ぶん。Required・Shortfall は人数に合わせたあとのレシピ側の単位
+type CookItem struct {
+	CatalogID int      `json:"catalog_id"`
+	Name      string   `json:"name"`
+	Amount    string   `json:"amount"`
+	Required  *float64 `json:"required,omitempty"`
+	Unit      string   `json:"unit,omitempty"`
+	Shortfall *float64 `json:"shortfall,omitempty"`
+	Skipped   string   `json:"skipped,omitempty"`
+}
+
+type CookPlan struct {
+	RecipeID     int             `json:"recipe_id"`
+	RecipeName   string          `json:"recipe_name"`
+	Servings     float64         `json:"servings"`
+	BaseServings *float64        `json:"base_servings"` // yield から読めなければ null（倍率 1 で扱う）
+	Scale        float64         `json:"scale"`
+	Items        []CookItem      `json:"items"`
+	Decrements   []CookDecrement `json:"decrements"`
+}
+
+type CookLogEntry struct {
+	ID         int     `json:"id"`
+	RecipeID   int     `json:"recipe_id,omitempty"`
+	RecipeName string  `json:"recipe_name"`
+	Servings   float64 `json:"servings"`
+	Notes      string  `json:"notes"`
+	CookedOn   string  `json:"cooked_on"`
+	CreatedBy  string  `json:"created_by"`
+	CreatedAt  string  `json:"created_at"`
+}
+
+// POST /api/recipes/cook?id=1 {"servings": 3, "confirm": false, "notes": "", "date": "2026-01-02"}
+//
+//	confirm が false（省略時）なら減らす予定だけ返す。true なら在庫を減らして cook_log に記録する
+//
+// GET /api/recipes/cook?id=1&limit=50  作った記録（id 省略時は全レシピ）
+func handleRecipeCook(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		getCookLog(w, r)
+	case "POST":
+		cookRecipe(w, r)
+	default:
+		sendJSONError(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func cookRecipe(w http.ResponseWriter, r *http.Request) {
+	recipeID, err := strconv.Atoi(r.URL.Query().Get("id"))
+	if err != nil || recipeID == 0 {
+		sendJSONError(w, "id required", http.StatusBadRequest)
+		return
+	}
+	var req struct {
+		Servings float64 `json:"servings"`
+		Confirm  bool    `json:"confirm"`
+		Notes    string  `json:"notes"`
+		Date     string  `json:"date"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			sendJSONError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	if req.Servings < 0 {
+		sendJSONError(w, "servings must be positive", http.StatusBadRequest)
+		return
+	}
+	cookedOn := time.Now().Format("2006-01-02")
+	if req.Date != "" {
+		d, err := time.Parse("2006-01-02", req.Date)
+		if err != nil {
+			sendJSONError(w, "date は YYYY-MM-DD 形式で指定してください", http.StatusBadRequest)
+			return
+		}
+		cookedOn = d.Format("2006-01-02")
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		sendJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	plan, err := buildCookPlan(tx, recipeID, req.Servings)
+	if err == sql.ErrNoRows {
+		tx.Rollback()
+		sendJSONError(w, "recipe not found", http.StatusNotFound)
+		return
+	} else if errors.Is(err, errCookServingsUnknown) {
+		tx.Rollback()
+		sendJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	} else if err != nil {
+		tx.Rollback()
+		sendJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if !req.Confirm {
+		tx.Rollback()
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{"status": "preview", "plan": plan})
+		return
+	}
+
+	note := fmt.Sprintf("料理「%s」", plan.RecipeName)
+	for _, d := range plan.Decrements {
+		if _, err := consumeIngredient(tx, r, d.IngredientID, d.Amount, note); err != nil {
+			tx.Rollback()
+			sendJSONError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	actor := ""
+	if u := currentUser(r); u != nil {
+		actor = u.Username
+	}
+	res, err := tx.Exec("INSERT INTO cook_log(recipe_id, recipe_name, servings, notes, cooked_on, created_by) VALUES(?, ?, ?, ?, ?, ?)",
+		plan.RecipeID, plan.RecipeName, plan.Servings, req.Notes, cookedOn, actor)
+	if err != nil {
+		tx.Rollback()
+		sendJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		sendJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	logID, _ := res.LastInsertId()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"status": "cooked", "cook_log_id": logID, "plan": plan})
+}
+
+// 人数に合わせて材料を増減し、期限の早い在庫から順に減らす予定を立てる（DB は変更しない）
+// servings が 0 ならレシピの人数のまま作る。yield から人数を読めないレシピで servings を指定すると
+// errCookServingsUnknown（倍率が分からないまま別の人数で記録しないため）
+func buildCookPlan(tx *sql.Tx, recipeID int, servings float64) (*CookPlan, error) {
+	plan := &CookPlan{RecipeID: recipeID, Scale: 1, Items: []CookItem{}, Decrements: []CookDecrement{}}
+	var yield sql.NullString
+	if err := tx.QueryRow("SELECT name, yield FROM recipes WHERE id = ? AND deleted_at IS NULL", recipeID).Scan(&plan.RecipeName, &yield); err != nil {
+		return nil, err
+	}
+	if base, ok := quantity.ParseServings(yield.String); ok {
+		plan.BaseServings = &base
+		if servings > 0 {
+			plan.Scale = servings / base
+		} else {
+			servings = base
+		}
+	} else if servings > 0 {
+		return nil, fmt.Errorf("%w: %s", errCookServingsUnknown, yield.String)
+	}
+	plan.Servings = servings
+
+	rows, err := tx.Query(`
+		SELECT ri.catalog_id, c.name, c.classification, ri.amount,
+			ri.quantity_value, ri.quantity_max, ri.quantity_unit, ri.quantity_qualifier
+		FROM recipe_ingredients ri
+		JOIN item_catalog c ON ri.catalog_id = c.id
+		WHERE ri.recipe_id = ?
+		ORDER BY ri.id ASC`, recipeID)
+	if err != nil {
+		return nil, err
+	}
+	type recipeLine struct {
+		item           CookItem
+		classification string
+		qty            quantity.Quantity
+	}
+	var lines []recipeLine
+	for rows.Next() {
+		var l recipeLine
+		var amount sql.NullString
+		var qv, qm sql.NullFloat64
+		var qu, qq sql.NullString
+		if err := rows.Scan(&l.item.CatalogID, &l.item.Name, &l.classification, &amount, &qv, &qm, &qu, &qq); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		l.item.Amount = amount.String
+		l.qty = quantityFromColumns(qv, qm, qu, qq)
+		lines = append(lines, l)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// 同じ食材が2行あっても二重に減らさないよう、在庫の残りはここで持ち回る
+	remaining := make(map[int]float64)
+	for _, l := range lines {
+		item := l.item
+		switch {
+		case l.classification == "調味料":
+			item.Skipped = CookSkipSeasoning
+		case !l.qty.HasValue():
+			item.Skipped = CookSkipNoQuantity
+		default:
+			required := roundAmount(l.qty.Value * plan.Scale)
+			item.Required = floatPtr(required)
+			item.Unit = l.qty.Unit
+			decrements, short, skipped, err := planDecrements(tx, item.CatalogID, item.Name, required, item.Unit, remaining)
+			if err != nil {
+				return nil, err
+			}
+			plan.Decrements = append(plan.Decrements, decrements...)
+			item.Skipped = skipped
+			if short > 0 && skipped == "" {
+				item.Shortfall = floatPtr(roundAmount(short))
+			}
+		}
+		plan.Items = append(plan.Items, item)
+	}
+	return plan, nil
+}
+
+// required（unit 単位）ぶんを期限の早い在庫から取る。期限なしの在庫は最後。
+// 取りきれなかった量と、量不明・換算不可の在庫があった場合はその理由を返す
+func planDecrements(tx *sql.Tx, catalogID int, name string, required float64, unit string, remaining map[int]float64) ([]CookDecrement, float64, string, error) {
+	factors, err := loadConversionFactors(tx, catalogID)
+	if err != nil {
+		return nil, 0, "", err
+	}
+	rows, err := tx.Query(`
+		SELECT i.id, i.amount, COALESCE(i.unit, ''), COALESCE(i.expiration_date, ''), l.name
+		FROM refrigerator_ingredients i
+		JOIN locations l ON i.location_id = l.id
+		WHERE i.catalog_id = ?
+		ORDER BY (i.expiration_date IS NULL OR i.expiration_date = '') ASC, i.expiration_date ASC, i.id ASC`, catalogID)
+	if err != nil {
+		return nil, 0, "", err
+	}
+	defer rows.Close()
+
+	var decrements []CookDecrement
+	need := required
+	skipped := ""
+	for rows.Next() && need > 0 {
+		d := CookDecrement{CatalogID: catalogID, Name: name}
+		if err := rows.Scan(&d.IngredientID, &d.Before, &d.Unit, &d.ExpirationDate, &d.Location); err != nil {
+			return nil, 0, "", err
+		}
+		if left, ok := remaining[d.IngredientID]; ok {
+			d.Before = left
+		}
+		if d.Before < 0 {
+			skipped = CookSkipUnknownAmount
+			continue
+		}
+		if d.Before == 0 {
+			continue
+		}
+		// 在庫の単位 1 あたりがレシピの単位でいくつになるか
+		per, err := units.Convert(1, d.Unit, unit, factors)
+		if err != nil || per.Value <= 0 {
+			skipped = CookSkipNotConvert
+			continue
+		}
+		available := d.Before * per.Value
+		if available <= need {
+			d.Amount = d.Before
+			need -= available
+		} else {
+			d.Amount = roundAmount(need / per.Value)
+			need = 0
+		}
+		d.After = roundAmount(d.Before - d.Amount)
+		if d.After <= 0 {
+			d.Amount, d.After = d.Before, 0
+		}
+		remaining[d.IngredientID] = d.After
+		decrements = append(decrements, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, "", err
+	}
+	if need <= 0 {
+		skipped = ""
+	}
+	return decrements, need, skipped, nil
+}
+
+func getCookLog(w http.ResponseWriter, r *http.Request) {
+	query := `SELECT id, COALESCE(recipe_id, 0), recipe_name, COALESCE(servings, 0), COALESCE(notes, ''),
+		cooked_on, COALESCE(created_by, ''), created_at FROM cook_log`
+	var args []interface{}
+	if s := r.URL.Query().Get("id"); s != "" {
+		id, err := strconv.Atoi(s)
+		if err != nil {
+			sendJSONError(w, "id must be a number", http.StatusBadRequest)
+			return
+		}
+		query += " WHERE recipe_id = ?"
+		args = append(args, id)
+	}
+	limit := 50
+	if s := r.URL.Query().Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n <= 0 {
+			sendJSONError(w, "limit must be positive", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+	query += " ORDER BY cooked_on DESC, id DESC LIMIT ?"
+	args = append(args, limit)
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		sendJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	entries := []CookLogEntry{}
+	for rows.Next() {
+		var e CookLogEntry
+		if err := rows.Scan(&e.ID, &e.RecipeID, &e.RecipeName, &e.Servings, &e.Notes, &e.CookedOn, &e.CreatedBy, &e.CreatedAt); err != nil {
+			sendJSONError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		entries = append(entries, e)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(entries)
+}
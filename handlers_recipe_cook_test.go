@@ -0,0 +1,234 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// 生姜焼き（2人分）と、その材料の在庫を入れる。玉ねぎは期限の違う3行、卵は量不明
+type cookFixture struct {
+	recipeID               int
+	onionSoon, onionLater  int // 期限 10/19（野菜室）・10/20（冷蔵庫）
+	onionNoExpiry, porkRow int
+}
+
+func newCookFixture(t *testing.T) cookFixture {
+	t.Helper()
+	newTestDB(t)
+	catalogID := func(name, classification string) int {
+		return mustInsert(t, "INSERT INTO item_catalog(name, classification) VALUES(?, ?)", name, classification)
+	}
+	onion := catalogID("玉ねぎ", "食材")
+	pork := catalogID("豚肉", "食材")
+	egg := catalogID("卵", "食材")
+	pepper := catalogID("こしょう", "食材")
+	soy := catalogID("醤油", "調味料")
+
+	stock := func(cid int, amount float64, unit, expiration, location string) int {
+		return mustInsert(t, `INSERT INTO refrigerator_ingredients(catalog_id, amount, unit, expiration_date, location_id)
+			VALUES(?, ?, ?, ?, (SELECT id FROM locations WHERE name = ?))`, cid, amount, unit, expiration, location)
+	}
+	var f cookFixture
+	f.onionLater = stock(onion, 1, "個", "2026-10-20", "冷蔵庫")
+	f.onionNoExpiry = stock(onion, 1, "個", "", "常温")
+	f.onionSoon = stock(onion, 2, "個", "2026-10-19", "野菜室")
+	f.porkRow = stock(pork, 300, "g", "2026-10-22", "冷蔵庫")
+	stock(egg, -1, "個", "", "冷蔵庫")
+
+	f.recipeID = mustInsert(t, "INSERT INTO recipes(name, yield) VALUES('生姜焼き', '2人分')")
+	ing := func(cid int, amount string, value float64, unit, qualifier string) {
+		mustInsert(t, `INSERT INTO recipe_ingredients(recipe_id, catalog_id, amount, quantity_value, quantity_unit, quantity_qualifier)
+			VALUES(?, ?, ?, ?, ?, ?)`, f.recipeID, cid, amount, value, unit, qualifier)
+	}
+	ing(pork, "200g", 200, "g", "")
+	ing(onion, "1個", 1, "個", "")
+	ing(onion, "1個", 1, "個", "") // タレ用にもう1個
+	ing(soy, "大さじ2", 2, "大さじ", "")
+	ing(pepper, "少々", 0, "", "少々")
+	ing(egg, "1個", 1, "個", "")
+	return f
+}
+
+type decrementSummary struct {
+	IngredientID  int
+	Location      string
+	Amount, After float64
+}
+
+func summarizeDecrements(ds []CookDecrement) []decrementSummary {
+	s := []decrementSummary{}
+	for _, d := range ds {
+		s = append(s, decrementSummary{d.IngredientID, d.Location, d.Amount, d.After})
+	}
+	return s
+}
+
+func TestBuildCookPlan(t *testing.T) {
+	f := newCookFixture(t)
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+
+	// 3人分 = 1.5倍。玉ねぎは2行とも期限の早い在庫から取り、同じ行を二重に数えない
+	plan, err := buildCookPlan(tx, f.recipeID, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plan.Scale != 1.5 || plan.Servings != 3 || plan.BaseServings == nil || *plan.BaseServings != 2 {
+		t.Errorf("plan = scale %v, servings %v, base %v", plan.Scale, plan.Servings, plan.BaseServings)
+	}
+	wantDecrements := []decrementSummary{
+		{f.porkRow, "冷蔵庫", 300, 0},
+		{f.onionSoon, "野菜室", 1.5, 0.5},
+		{f.onionSoon, "野菜室", 0.5, 0},
+		{f.onionLater, "冷蔵庫", 1, 0},
+	}
+	if got := summarizeDecrements(plan.Decrements); !reflect.DeepEqual(got, wantDecrements) {
+		t.Errorf("decrements =\n%+v\nwant\n%+v", got, wantDecrements)
+	}
+
+	type itemSummary struct {
+		Name      string
+		Required  float64
+		Shortfall float64
+		Skipped   string
+	}
+	var items []itemSummary
+	for _, it := range plan.Items {
+		s := itemSummary{Name: it.Name, Skipped: it.Skipped}
+		if it.Required != nil {
+			s.Required = *it.Required
+		}
+		if it.Shortfall != nil {
+			s.Shortfall = *it.Shortfall
+		}
+		items = append(items, s)
+	}
+	wantItems := []itemSummary{
+		{"豚肉", 300, 0, ""},
+		{"玉ねぎ", 1.5, 0, ""},
+		{"玉ねぎ", 1.5, 0, ""},
+		{"醤油", 0, 0, CookSkipSeasoning},
+		{"こしょう", 0, 0, CookSkipNoQuantity},
+		{"卵", 1.5, 0, CookSkipUnknownAmount},
+	}
+	if !reflect.DeepEqual(items, wantItems) {
+		t.Errorf("items =\n%+v\nwant\n%+v", items, wantItems)
+	}
+
+	// 4人分だと豚肉が 100g 足りない
+	plan, err = buildCookPlan(tx, f.recipeID, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sf := plan.Items[0].Shortfall; sf == nil || *sf != 100 {
+		t.Errorf("豚肉 shortfall = %v, want 100", sf)
+	}
+}
+
+func TestBuildCookPlanServingsUnknown(t *testing.T) {
+	f := newCookFixture(t)
+	if _, err := db.Exec("UPDATE recipes SET yield = '適量' WHERE id = ?", f.recipeID); err != nil {
+		t.Fatal(err)
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+
+	// 人数を指定しなければ倍率 1 で作れる
+	plan, err := buildCookPlan(tx, f.recipeID, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plan.Scale != 1 || plan.BaseServings != nil {
+		t.Errorf("plan = scale %v, base %v, want 1, nil", plan.Scale, plan.BaseServings)
+	}
+	if _, err := buildCookPlan(tx, f.recipeID, 3); !errors.Is(err, errCookServingsUnknown) {
+		t.Errorf("buildCookPlan(servings 3) error = %v, want errCookServingsUnknown", err)
+	}
+}
+
+func TestCookRecipe(t *testing.T) {
+	f := newCookFixture(t)
+	cook := func(id, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handleRecipeCook(rec, httptest.NewRequest("POST", "/api/recipes/cook?id="+id, strings.NewReader(body)))
+		return rec
+	}
+	amount := func(id int) float64 {
+		var a float64
+		if err := db.QueryRow("SELECT amount FROM refrigerator_ingredients WHERE id = ?", id).Scan(&a); err != nil {
+			return 0
+		}
+		return a
+	}
+
+	// プレビューでは在庫を変えない
+	if rec := cook(strconv.Itoa(f.recipeID), `{"servings": 2}`); rec.Code != http.StatusOK {
+		t.Fatalf("preview = %d %s", rec.Code, rec.Body)
+	}
+	if got := amount(f.onionSoon); got != 2 {
+		t.Errorf("玉ねぎ after preview = %v, want 2", got)
+	}
+
+	rec := cook(strconv.Itoa(f.recipeID), `{"confirm": true, "notes": "おいしかった", "date": "2026-10-18"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("cook = %d %s", rec.Code, rec.Body)
+	}
+	var res struct {
+		Status    string `json:"status"`
+		CookLogID int    `json:"cook_log_id"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&res); err != nil || res.Status != "cooked" {
+		t.Fatalf("cook response = %+v (%v)", res, err)
+	}
+	// 2人分: 豚肉 200g、玉ねぎは 10/19 の2個を使い切る
+	if got := amount(f.porkRow); got != 100 {
+		t.Errorf("豚肉 = %v, want 100", got)
+	}
+	if got := amount(f.onionSoon); got != 0 {
+		t.Errorf("玉ねぎ（10/19）= %v, want 0 (deleted)", got)
+	}
+	if got := amount(f.onionLater); got != 1 {
+		t.Errorf("玉ねぎ（10/20）= %v, want 1", got)
+	}
+	if got := amount(f.onionNoExpiry); got != 1 {
+		t.Errorf("玉ねぎ（期限なし）= %v, want 1", got)
+	}
+	var name, notes, cookedOn string
+	var servings float64
+	err := db.QueryRow("SELECT recipe_name, servings, notes, cooked_on FROM cook_log WHERE id = ?", res.CookLogID).
+		Scan(&name, &servings, &notes, &cookedOn)
+	if err != nil || name != "生姜焼き" || servings != 2 || notes != "おいしかった" || cookedOn != "2026-10-18" {
+		t.Errorf("cook_log = %s %v %s %s (%v)", name, servings, notes, cookedOn, err)
+	}
+
+	if _, err := db.Exec("UPDATE recipes SET yield = '' WHERE id = ?", f.recipeID); err != nil {
+		t.Fatal(err)
+	}
+	if rec := cook(strconv.Itoa(f.recipeID), `{"servings": 3}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("servings without yield = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+	if rec := cook(strconv.Itoa(f.recipeID), `{"date": "10/18"}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("bad date = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+	if rec := cook("999", `{}`); rec.Code != http.StatusNotFound {
+		t.Errorf("missing recipe = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+	if _, err := db.Exec("UPDATE recipes SET deleted_at = CURRENT_TIMESTAMP WHERE id = ?", f.recipeID); err != nil {
+		t.Fatal(err)
+	}
+	if rec := cook(strconv.Itoa(f.recipeID), `{}`); rec.Code != http.StatusNotFound {
+		t.Errorf("trashed recipe = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
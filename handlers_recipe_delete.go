@@ -0,0 +1,283 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+type TrashedRecipe struct {
+	ID        int    `json:"id"`
+	Name      string `json:"name"`
+	URL       string `json:"url"`
+	DeletedAt string `json:"deleted_at"`
+}
+
+// DELETE /api/recipes?id=1            1件をゴミ箱へ
+// DELETE /api/recipes?ids=1,2,3       まとめてゴミ箱へ
+// DELETE /api/recipes?url=https://…   URL が一致するレシピをゴミ箱へ
+// DELETE /api/recipes?source=example.com  URL のホストが一致するレシピ（サブドメイン含む）をゴミ箱へ
+// purge=true を付けるとゴミ箱を経由せずに材料ごと削除する
+func deleteRecipes(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	purge := q.Get("purge") == "true"
+
+	tx, err := db.Begin()
+	if err != nil {
+		sendJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	ids, err := selectRecipes(tx, q, false)
+	if _, ok := err.(recipeSelectorError); ok {
+		tx.Rollback()
+		sendJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	} else if err != nil {
+		tx.Rollback()
+		sendJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if len(ids) == 0 {
+		tx.Rollback()
+		sendJSONError(w, "not found", http.StatusNotFound)
+		return
+	}
+
+	for _, id := range ids {
+		if purge {
+			err = purgeRecipe(tx, r, id)
+		} else {
+			err = setRecipeDeleted(tx, r, id, true)
+		}
+		if err != nil {
+			tx.Rollback()
+			sendJSONError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		sendJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	status := "trashed"
+	if purge {
+		status = "deleted"
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"status": status, "count": len(ids), "ids": ids})
+}
+
+// GET    /api/recipes/trash          ゴミ箱のレシピ一覧
+// DELETE /api/recipes/trash?ids=1,2  ゴミ箱から完全に削除（ids・url・source 省略時はゴミ箱を空にする）
+func handleRecipeTrash(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		getRecipeTrash(w, r)
+	case "DELETE":
+		emptyRecipeTrash(w, r)
+	default:
+		sendJSONError(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func getRecipeTrash(w http.ResponseWriter, _ *http.Request) {
+	rows, err := db.Query("SELECT id, name, COALESCE(url, ''), deleted_at FROM recipes WHERE deleted_at IS NOT NULL ORDER BY deleted_at DESC")
+	if err != nil {
+		sendJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	recipes := []TrashedRecipe{}
+	for rows.Next() {
+		var t TrashedRecipe
+		if err := rows.Scan(&t.ID, &t.Name, &t.URL, &t.DeletedAt); err != nil {
+			sendJSONError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		recipes = append(recipes, t)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(recipes)
+}
+
+func emptyRecipeTrash(w http.ResponseWriter, r *http.Request) {
+	tx, err := db.Begin()
+	if err != nil {
+		sendJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	ids, err := selectRecipes(tx, r.URL.Query(), true)
+	if err == errNoRecipeSelector {
+		ids, err = selectIDs(tx, "SELECT id FROM recipes WHERE deleted_at IS NOT NULL")
+	}
+	if _, ok := err.(recipeSelectorError); ok {
+		tx.Rollback()
+		sendJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	} else if err != nil {
+		tx.Rollback()
+		sendJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	for _, id := range ids {
+		if err := purgeRecipe(tx, r, id); err != nil {
+			tx.Rollback()
+			sendJSONError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		sendJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"status": "deleted", "count": len(ids), "ids": ids})
+}
+
+// POST /api/recipes/restore?ids=1,2  ゴミ箱から戻す（id・url・source でも指定できる）
+func handleRecipeRestore(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		sendJSONError(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		sendJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	ids, err := selectRecipes(tx, r.URL.Query(), true)
+	if _, ok := err.(recipeSelectorError); ok {
+		tx.Rollback()
+		sendJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	} else if err != nil {
+		tx.Rollback()
+		sendJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if len(ids) == 0 {
+		tx.Rollback()
+		sendJSONError(w, "not found", http.StatusNotFound)
+		return
+	}
+	for _, id := range ids {
+		if err := setRecipeDeleted(tx, r, id, false); err != nil {
+			tx.Rollback()
+			sendJSONError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		sendJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"status": "restored", "count": len(ids), "ids": ids})
+}
+
+// レシピの指定方法が間違っているときのエラー（400 で返す）
+type recipeSelectorError string
+
+func (e recipeSelectorError) Error() string { return string(e) }
+
+const errNoRecipeSelector = recipeSelectorError("id, ids, url, source のいずれかを指定してください")
+
+// クエリの id / ids / url / source に当てはまるレシピの id を返す。
+// trashed が true ならゴミ箱の中から、false ならゴミ箱以外から探す
+func selectRecipes(tx *sql.Tx, q url.Values, trashed bool) ([]int, error) {
+	where := "deleted_at IS NULL"
+	if trashed {
+		where = "deleted_at IS NOT NULL"
+	}
+
+	switch {
+	case q.Get("id") != "" || q.Get("ids") != "":
+		ids, err := parseIDList(q.Get("id") + "," + q.Get("ids"))
+		if err != nil {
+			return nil, recipeSelectorError(err.Error())
+		}
+		if len(ids) == 0 {
+			return nil, errNoRecipeSelector
+		}
+		args := make([]interface{}, len(ids))
+		placeholders := make([]string, len(ids))
+		for i, id := range ids {
+			args[i] = id
+			placeholders[i] = "?"
+		}
+		return selectIDs(tx, "SELECT id FROM recipes WHERE "+where+" AND id IN ("+strings.Join(placeholders, ",")+") ORDER BY id", args...)
+	case q.Get("url") != "":
+		return selectIDs(tx, "SELECT id FROM recipes WHERE "+where+" AND url = ? ORDER BY id", q.Get("url"))
+	case q.Get("source") != "":
+		return selectRecipesBySource(tx, where, strings.ToLower(strings.TrimSpace(q.Get("source"))))
+	}
+	return nil, errNoRecipeSelector
+}
+
+func selectRecipesBySource(tx *sql.Tx, where, source string) ([]int, error) {
+	rows, err := tx.Query("SELECT id, url FROM recipes WHERE " + where + " AND url IS NOT NULL AND url != '' ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := []int{}
+	for rows.Next() {
+		var id int
+		var raw string
+		if err := rows.Scan(&id, &raw); err != nil {
+			return nil, err
+		}
+		u, err := url.Parse(raw)
+		if err != nil {
+			continue
+		}
+		host := strings.ToLower(u.Hostname())
+		if host == source || strings.HasSuffix(host, "."+source) {
+			ids = append(ids, id)
+		}
+	}
+	return ids, rows.Err()
+}
+
+func setRecipeDeleted(tx *sql.Tx, r *http.Request, id int, deleted bool) error {
+	before, err := snapshotRow(tx, "recipes", id)
+	if err != nil || before == nil {
+		return err
+	}
+	query := "UPDATE recipes SET deleted_at = NULL WHERE id = ?"
+	if deleted {
+		query = "UPDATE recipes SET deleted_at = datetime('now','localtime') WHERE id = ?"
+	}
+	if _, err := tx.Exec(query, id); err != nil {
+		return err
+	}
+	after, err := snapshotRow(tx, "recipes", id)
+	if err != nil {
+		return err
+	}
+	return writeAudit(tx, r, AuditRecipe, id, AuditUpdate, before, after)
+}
+
+// レシピを材料ごと削除する。買い物リストと料理の記録はレシピへの参照だけ外して残す
+func purgeRecipe(tx *sql.Tx, r *http.Request, id int) error {
+	before, err := snapshotRecipe(tx, id)
+	if err != nil || before == nil {
+		return err
+	}
+	for _, stmt := range []string{
+		"DELETE FROM recipe_ingredients WHERE recipe_id = ?",
+		"UPDATE shopping_list SET recipe_id = NULL WHERE recipe_id = ?",
+		"UPDATE cook_log SET recipe_id = NULL WHERE recipe_id = ?",
+		"DELETE FROM recipes WHERE id = ?",
+	} {
+		if _, err := tx.Exec(stmt, id); err != nil {
+			return err
+		}
+	}
+	return writeAudit(tx, r, AuditRecipe, id, AuditDelete, before, nil)
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+type recipeBatchResult struct {
+	Status string `json:"status"`
+	IDs    []int  `json:"ids"`
+}
+
+func TestRecipeTrashAndRestore(t *testing.T) {
+	newTestDB(t)
+	onion := mustInsert(t, "INSERT INTO item_catalog(name, classification) VALUES('玉ねぎ', '食材')")
+	recipe := func(name, url string) int {
+		id := mustInsert(t, "INSERT INTO recipes(name, url) VALUES(?, ?)", name, url)
+		mustInsert(t, "INSERT INTO recipe_ingredients(recipe_id, catalog_id, amount) VALUES(?, ?, '1個')", id, onion)
+		return id
+	}
+	curry := recipe("カレー", "https://www.example.com/curry")
+	stew := recipe("シチュー", "https://cookpad.example.org/stew")
+	soup := recipe("スープ", "https://example.com/soup")
+	mustInsert(t, "INSERT INTO shopping_list(catalog_id, recipe_id) VALUES(?, ?)", onion, curry)
+	mustInsert(t, "INSERT INTO cook_log(recipe_id, recipe_name, cooked_on) VALUES(?, 'カレー', '2026-10-18')", curry)
+
+	do := func(handler http.HandlerFunc, method, target string, wantCode int) recipeBatchResult {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		handler(rec, httptest.NewRequest(method, target, nil))
+		if rec.Code != wantCode {
+			t.Fatalf("%s %s = %d %s, want %d", method, target, rec.Code, rec.Body, wantCode)
+		}
+		var res recipeBatchResult
+		if wantCode == http.StatusOK {
+			json.NewDecoder(rec.Body).Decode(&res)
+		}
+		return res
+	}
+	trashed := func() []int {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		handleRecipeTrash(rec, httptest.NewRequest("GET", "/api/recipes/trash", nil))
+		var list []TrashedRecipe
+		if err := json.NewDecoder(rec.Body).Decode(&list); err != nil {
+			t.Fatal(err)
+		}
+		ids := []int{}
+		for _, r := range list {
+			ids = append(ids, r.ID)
+		}
+		return ids
+	}
+	exists := func(query string, args ...interface{}) bool {
+		t.Helper()
+		var n int
+		if err := db.QueryRow("SELECT COUNT(*) FROM "+query, args...).Scan(&n); err != nil {
+			t.Fatal(err)
+		}
+		return n > 0
+	}
+
+	// 指定の誤り
+	do(deleteRecipes, "DELETE", "/api/recipes", http.StatusBadRequest)
+	do(deleteRecipes, "DELETE", "/api/recipes?ids=1,x", http.StatusBadRequest)
+	do(handleRecipeRestore, "POST", "/api/recipes/restore", http.StatusBadRequest)
+
+	// ゴミ箱へ。ゴミ箱の中のレシピはもう一度は消せない
+	res := do(deleteRecipes, "DELETE", "/api/recipes?source=example.com", http.StatusOK)
+	if res.Status != "trashed" || !reflect.DeepEqual(res.IDs, []int{curry, soup}) {
+		t.Errorf("trash by source = %+v, want %v", res, []int{curry, soup})
+	}
+	do(deleteRecipes, "DELETE", "/api/recipes?id="+strconv.Itoa(curry), http.StatusNotFound)
+	if got := trashed(); len(got) != 2 {
+		t.Errorf("trash = %v, want 2 recipes", got)
+	}
+	if !exists("recipe_ingredients WHERE recipe_id = ?", curry) {
+		t.Error("ゴミ箱に入れたレシピの材料が消えた")
+	}
+
+	// 戻す。ゴミ箱に無いレシピは戻せない
+	res = do(handleRecipeRestore, "POST", "/api/recipes/restore?ids="+strconv.Itoa(soup), http.StatusOK)
+	if res.Status != "restored" || !reflect.DeepEqual(res.IDs, []int{soup}) {
+		t.Errorf("restore = %+v, want %v", res, []int{soup})
+	}
+	do(handleRecipeRestore, "POST", "/api/recipes/restore?ids="+strconv.Itoa(stew), http.StatusNotFound)
+	if got := trashed(); !reflect.DeepEqual(got, []int{curry}) {
+		t.Errorf("trash = %v, want %v", got, []int{curry})
+	}
+
+	// ゴミ箱から完全に削除。ゴミ箱に無いスープは source に当てはまっても消さない
+	res = do(handleRecipeTrash, "DELETE", "/api/recipes/trash?source=example.com", http.StatusOK)
+	if res.Status != "deleted" || !reflect.DeepEqual(res.IDs, []int{curry}) {
+		t.Errorf("purge = %+v, want %v", res, []int{curry})
+	}
+	if exists("recipes WHERE id = ?", curry) || exists("recipe_ingredients WHERE recipe_id = ?", curry) {
+		t.Error("完全に削除したレシピが残っている")
+	}
+	if !exists("shopping_list WHERE recipe_id IS NULL") || !exists("cook_log WHERE recipe_id IS NULL AND recipe_name = 'カレー'") {
+		t.Error("買い物リストと料理の記録はレシピへの参照だけ外して残す")
+	}
+	if !exists("audit_log WHERE entity_id = ? AND action = ?", curry, AuditDelete) {
+		t.Error("完全に削除した記録が audit_log に無い")
+	}
+
+	// purge=true はゴミ箱を経由しない
+	res = do(deleteRecipes, "DELETE", "/api/recipes?purge=true&url=https://cookpad.example.org/stew", http.StatusOK)
+	if res.Status != "deleted" || exists("recipes WHERE id = ?", stew) {
+		t.Errorf("purge=true = %+v, recipe left = %v", res, exists("recipes WHERE id = ?", stew))
+	}
+
+	// 指定なしはゴミ箱を空にする
+	do(deleteRecipes, "DELETE", "/api/recipes?id="+strconv.Itoa(soup), http.StatusOK)
+	res = do(handleRecipeTrash, "DELETE", "/api/recipes/trash", http.StatusOK)
+	if !reflect.DeepEqual(res.IDs, []int{soup}) || len(trashed()) != 0 {
+		t.Errorf("empty trash = %+v, trash left = %v", res, trashed())
+	}
+}
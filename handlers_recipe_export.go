@@ -0,0 +1,404 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type exportIngredient struct {
+	Name    string
+	Amount  string
+	Group   string
+	Details string
+}
+
+type exportRecipe struct {
+	ID          int
+	Name        string
+	Yield       string
+	Process     string
+	URL         string
+	CreatedAt   string
+	Ingredients []exportIngredient
+}
+
+type recipeExportFormat struct {
+	Ext         string
+	ContentType string
+	Render      func(*exportRecipe) ([]byte, error)
+}
+
+// format パラメータの値ごとの書き出し方
+var recipeExportFormats = map[string]recipeExportFormat{
+	"jsonld":   {".jsonld", "application/ld+json; charset=utf-8", renderRecipeJSONLD},
+	"markdown": {".md", "text/markdown; charset=utf-8", renderRecipeMarkdown},
+	"html":     {".html", "text/html; charset=utf-8", renderRecipeHTML},
+}
+
+// zip に入れる順
+var recipeExportFormatNames = []string{"jsonld", "markdown", "html"}
+
+// GET /api/recipes/export?id=3&format=markdown  1件を jsonld（既定）/ markdown / html で書き出す
+// GET /api/recipes/export?format=jsonld         id を省略するとゴミ箱以外の全レシピを zip にまとめる
+// zip では format を省略すると3つの形式をすべて入れる。html は印刷用のページなのでブラウザで開く
+func handleRecipeExport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		sendJSONError(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	q := r.URL.Query()
+
+	format := q.Get("format")
+	if _, ok := recipeExportFormats[format]; format != "" && !ok {
+		sendJSONError(w, "format は jsonld / markdown / html のどれかを指定してください", http.StatusBadRequest)
+		return
+	}
+
+	if idStr := q.Get("id"); idStr != "" {
+		id, err := strconv.Atoi(idStr)
+		if err != nil {
+			sendJSONError(w, "id が不正です", http.StatusBadRequest)
+			return
+		}
+		recipes, err := loadExportRecipes(id)
+		if err != nil {
+			sendJSONError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if len(recipes) == 0 {
+			sendJSONError(w, "not found", http.StatusNotFound)
+			return
+		}
+		if format == "" {
+			format = "jsonld"
+		}
+		writeRecipeExport(w, &recipes[0], format)
+		return
+	}
+
+	formats := recipeExportFormatNames
+	if format != "" {
+		formats = []string{format}
+	}
+
+	recipes, err := loadExportRecipes(0)
+	if err != nil {
+		sendJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	// 途中で失敗してもエラーを返せるよう、いったんメモリ上で zip を作る
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	now := time.Now()
+	for i := range recipes {
+		for _, name := range formats {
+			f := recipeExportFormats[name]
+			body, err := f.Render(&recipes[i])
+			if err == nil {
+				err = addZipFile(zw, recipeExportFileName(&recipes[i], f.Ext), body, now)
+			}
+			if err != nil {
+				sendJSONError(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+	}
+	if err := zw.Close(); err != nil {
+		sendJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	fileName := fmt.Sprintf("recipes_export_%s.zip", now.Format("20060102150405"))
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", "attachment; filename=\""+fileName+"\"")
+	w.Write(buf.Bytes())
+}
+
+func addZipFile(zw *zip.Writer, name string, body []byte, modified time.Time) error {
+	fw, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: modified})
+	if err != nil {
+		return err
+	}
+	_, err = fw.Write(body)
+	return err
+}
+
+func writeRecipeExport(w http.ResponseWriter, recipe *exportRecipe, format string) {
+	f := recipeExportFormats[format]
+	body, err := f.Render(recipe)
+	if err != nil {
+		sendJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	// 印刷用の html はそのまま開き、それ以外はダウンロードさせる
+	disposition := "attachment"
+	if format == "html" {
+		disposition = "inline"
+	}
+	fileName := recipeExportFileName(recipe, f.Ext)
+	w.Header().Set("Content-Type", f.ContentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("%s; filename=\"recipe_%d%s\"; filename*=UTF-8''%s",
+		disposition, recipe.ID, f.Ext, url.PathEscape(fileName)))
+	w.Write(body)
+}
+
+// ファイル名に使えない文字
+var unsafeFileNameChars = regexp.MustCompile(`[\\/:*?"<>|\s]+`)
+
+// 「3_豚汁.md」のように id とレシピ名から付ける
+func recipeExportFileName(recipe *exportRecipe, ext string) string {
+	name := strings.Trim(unsafeFileNameChars.ReplaceAllString(recipe.Name, "_"), "_")
+	return fmt.Sprintf("%d_%s%s", recipe.ID, name, ext)
+}
+
+// id が 0 ならゴミ箱以外の全レシピ。材料は登録順
+func loadExportRecipes(id int) ([]exportRecipe, error) {
+	query := `SELECT id, name, COALESCE(yield, ''), COALESCE(process, ''), COALESCE(url, ''), COALESCE(created_at, '')
+		FROM recipes WHERE deleted_at IS NULL`
+	ingQuery := `SELECT ri.recipe_id, ic.name, COALESCE(ri.amount, ''), COALESCE(ri.unit, ''), COALESCE(ri.group_name, ''), COALESCE(ri.details, '')
+		FROM recipe_ingredients ri JOIN item_catalog ic ON ri.catalog_id = ic.id`
+	var args []interface{}
+	if id != 0 {
+		query += " AND id = ?"
+		ingQuery += " WHERE ri.recipe_id = ?"
+		args = append(args, id)
+	}
+
+	rows, err := db.Query(query+" ORDER BY id", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	recipes := []exportRecipe{}
+	index := make(map[int]int)
+	for rows.Next() {
+		var rec exportRecipe
+		if err := rows.Scan(&rec.ID, &rec.Name, &rec.Yield, &rec.Process, &rec.URL, &rec.CreatedAt); err != nil {
+			return nil, err
+		}
+		index[rec.ID] = len(recipes)
+		recipes = append(recipes, rec)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	ingRows, err := db.Query(ingQuery+" ORDER BY ri.recipe_id, ri.id", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer ingRows.Close()
+	for ingRows.Next() {
+		var recipeID int
+		var ing exportIngredient
+		var unit string
+		if err := ingRows.Scan(&recipeID, &ing.Name, &ing.Amount, &unit, &ing.Group, &ing.Details); err != nil {
+			return nil, err
+		}
+		// 古いデータは分量と単位が別のカラムに入っている
+		ing.Amount += unit
+		if i, ok := index[recipeID]; ok {
+			recipes[i].Ingredients = append(recipes[i].Ingredients, ing)
+		}
+	}
+	return recipes, ingRows.Err()
+}
+
+// 手順を1行1手順に分ける。「1.」「①」のような番号は付け直すので外す
+var stepNumberPattern = regexp.MustCompile(`^(\d+[.．)）]|[①-⑳])\s*`)
+
+func (rec *exportRecipe) steps() []string {
+	var steps []string
+	for _, line := range strings.Split(strings.ReplaceAll(rec.Process, "\r\n", "\n"), "\n") {
+		if line = strings.TrimSpace(stepNumberPattern.ReplaceAllString(strings.TrimSpace(line), "")); line != "" {
+			steps = append(steps, line)
+		}
+	}
+	return steps
+}
+
+type exportGroup struct {
+	Name        string
+	Ingredients []exportIngredient
+}
+
+// 材料を登録順のまま group_name ごとにまとめる
+func (rec *exportRecipe) groups() []exportGroup {
+	var groups []exportGroup
+	for _, ing := range rec.Ingredients {
+		if len(groups) == 0 || groups[len(groups)-1].Name != ing.Group {
+			groups = append(groups, exportGroup{Name: ing.Group})
+		}
+		g := &groups[len(groups)-1]
+		g.Ingredients = append(g.Ingredients, ing)
+	}
+	return groups
+}
+
+// 「醤油 小さじ1（お好みで）」
+func (ing exportIngredient) text() string {
+	s := strings.TrimSpace(ing.Name + " " + ing.Amount)
+	if ing.Details != "" {
+		s += "（" + ing.Details + "）"
+	}
+	return s
+}
+
+// schema.org の Recipe。recipeIngredient にはグループの区切りとして「【A】」の行を入れる
+// （多くのレシピサイトと同じ書き方で、/api/recipes/import_url で読み戻すとグループになる）
+func renderRecipeJSONLD(rec *exportRecipe) ([]byte, error) {
+	ingredients := []string{}
+	for _, g := range rec.groups() {
+		if g.Name != "" {
+			ingredients = append(ingredients, "【"+g.Name+"】")
+		}
+		for _, ing := range g.Ingredients {
+			ingredients = append(ingredients, ing.text())
+		}
+	}
+	steps := []map[string]string{}
+	for _, s := range rec.steps() {
+		steps = append(steps, map[string]string{"@type": "HowToStep", "text": s})
+	}
+
+	doc := map[string]interface{}{
+		"@context":           "https://schema.org",
+		"@type":              "Recipe",
+		"identifier":         strconv.Itoa(rec.ID),
+		"name":               rec.Name,
+		"recipeIngredient":   ingredients,
+		"recipeInstructions": steps,
+	}
+	if rec.Yield != "" {
+		doc["recipeYield"] = rec.Yield
+	}
+	if rec.URL != "" {
+		doc["isBasedOn"] = rec.URL
+	}
+	if rec.CreatedAt != "" {
+		doc["dateCreated"] = strings.Replace(rec.CreatedAt, " ", "T", 1)
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func renderRecipeMarkdown(rec *exportRecipe) ([]byte, error) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n", rec.Name)
+	if rec.Yield != "" {
+		fmt.Fprintf(&b, "- 分量: %s\n", rec.Yield)
+	}
+	if rec.URL != "" {
+		fmt.Fprintf(&b, "- 参照: <%s>\n", rec.URL)
+	}
+	if rec.Yield != "" || rec.URL != "" {
+		b.WriteString("\n")
+	}
+
+	b.WriteString("## 材料\n")
+	for i, g := range rec.groups() {
+		switch {
+		case g.Name != "":
+			fmt.Fprintf(&b, "\n### %s\n", g.Name)
+		case i > 0:
+			// グループの後にグループなしの材料が続くときは見出しで区切る
+			b.WriteString("\n### その他\n")
+		}
+		b.WriteString("\n")
+		for _, ing := range g.Ingredients {
+			fmt.Fprintf(&b, "- %s", ing.Name)
+			if ing.Amount != "" {
+				fmt.Fprintf(&b, " … %s", ing.Amount)
+			}
+			if ing.Details != "" {
+				fmt.Fprintf(&b, "（%s）", ing.Details)
+			}
+			b.WriteString("\n")
+		}
+	}
+
+	if steps := rec.steps(); len(steps) > 0 {
+		b.WriteString("\n## 作り方\n\n")
+		for i, s := range steps {
+			fmt.Fprintf(&b, "%d. %s\n", i+1, s)
+		}
+	}
+	return []byte(b.String()), nil
+}
+
+var recipePrintTemplate = template.Must(template.New("recipe").Parse(`<!DOCTYPE html>
+<html lang="ja">
+<head>
+<meta charset="utf-8">
+<title>{{.Name}}</title>
+<style>
+  body { font-family: "Hiragino Kaku Gothic ProN", "Noto Sans JP", sans-serif; max-width: 720px; margin: 24px auto; padding: 0 16px; color: #222; line-height: 1.6; }
+  h1 { font-size: 22px; border-bottom: 2px solid #e67e22; padding-bottom: 4px; margin-bottom: 4px; }
+  h2 { font-size: 16px; margin: 20px 0 8px; }
+  h3 { font-size: 14px; margin: 12px 0 4px; color: #555; }
+  .meta { font-size: 12px; color: #666; }
+  table { border-collapse: collapse; width: 100%; font-size: 14px; }
+  td { border-bottom: 1px dotted #ccc; padding: 3px 4px; vertical-align: top; }
+  td.amount { text-align: right; white-space: nowrap; width: 8em; }
+  td.details { color: #666; font-size: 12px; }
+  ol { padding-left: 1.6em; font-size: 14px; }
+  li { margin-bottom: 6px; }
+  .no-print { margin-top: 24px; text-align: center; }
+  @media print {
+    body { margin: 0; max-width: none; }
+    .no-print { display: none; }
+    h2, h3 { break-after: avoid; }
+    tr, li { break-inside: avoid; }
+  }
+</style>
+</head>
+<body>
+<h1>{{.Name}}</h1>
+<div class="meta">{{if .Yield}}{{.Yield}}{{end}}{{if .URL}}　{{.URL}}{{end}}</div>
+
+<h2>材料</h2>
+{{range $i, $g := .Groups}}{{if $g.Name}}<h3>{{$g.Name}}</h3>{{else if $i}}<h3>その他</h3>{{end}}
+<table>
+{{range $g.Ingredients}}<tr><td>{{.Name}}</td><td class="details">{{.Details}}</td><td class="amount">{{.Amount}}</td></tr>
+{{end}}</table>
+{{end}}
+{{if .Steps}}<h2>作り方</h2>
+<ol>
+{{range .Steps}}<li>{{.}}</li>
+{{end}}</ol>
+{{end}}
+<div class="no-print"><button onclick="window.print()">印刷する</button></div>
+</body>
+</html>
+`))
+
+func renderRecipeHTML(rec *exportRecipe) ([]byte, error) {
+	var buf bytes.Buffer
+	err := recipePrintTemplate.Execute(&buf, map[string]interface{}{
+		"Name":   rec.Name,
+		"Yield":  rec.Yield,
+		"URL":    rec.URL,
+		"Groups": rec.groups(),
+		"Steps":  rec.steps(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
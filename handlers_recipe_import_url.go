@@ -0,0 +1,189 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+
+	"kimichan/catalog"
+	"kimichan/recipetext"
+	"kimichan/recipeweb"
+)
+
+// ページの取得を待つ時間
+const recipeImportTimeout = 20 * time.Second
+
+// たどるリダイレクトの上限
+const recipeImportMaxRedirects = 5
+
+// サーバー自身や家のネットワーク（ルーター・NAS）、クラウドのメタデータには取りに行かない
+var errRecipeImportForbidden = errors.New("このアドレスのページは読み込めません")
+
+// レシピの取り込み用の HTTP クライアント。接続先の IP を接続の直前に調べるので、
+// DNS の応答やリダイレクトでプライベートアドレスに向けられても繋がない
+func recipeImportClient() *http.Client {
+	dialer := &net.Dialer{Timeout: 10 * time.Second, Control: rejectPrivateAddress}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil // プロキシ経由だとプロキシのアドレスしか調べられない
+	transport.DialContext = dialer.DialContext
+	return &http.Client{
+		Timeout:   recipeImportTimeout,
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= recipeImportMaxRedirects {
+				return fmt.Errorf("リダイレクトが多すぎます（%d回）", len(via))
+			}
+			if req.URL.Scheme != "http" && req.URL.Scheme != "https" || forbiddenImportHost(req.URL.Hostname()) {
+				return fmt.Errorf("%w: %s", errRecipeImportForbidden, req.URL.Host)
+			}
+			return nil
+		},
+	}
+}
+
+// 名前で分かるものは名前解決の前に断る（アドレスは rejectPrivateAddress でも調べる）
+func forbiddenImportHost(host string) bool {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	return host == "localhost" || strings.HasSuffix(host, ".localhost") || host == "metadata.google.internal"
+}
+
+// net.Dialer の Control。ループバック・プライベート（RFC1918 / ULA）・リンクローカル
+// （169.254.169.254 = metadata.google.internal を含む）・未指定・マルチキャストのアドレスを拒む
+func rejectPrivateAddress(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() {
+		return fmt.Errorf("%w: %s", errRecipeImportForbidden, host)
+	}
+	return nil
+}
+
+type ImportedIngredient struct {
+	recipetext.Line
+	CatalogID   int    `json:"catalog_id"` // 0 ならカタログに無い
+	CatalogName string `json:"catalog_name"`
+	Alias       string `json:"alias,omitempty"` // 別名で見つかったときの別名
+}
+
+type RecipeImportPreview struct {
+	Name                string                         `json:"name"`
+	Yield               string                         `json:"yield"`
+	URL                 string                         `json:"url"`
+	Process             string                         `json:"process"`
+	CsvData             string                         `json:"csv_data"`
+	OriginalIngredients string                         `json:"original_ingredients"`
+	OriginalProcess     string                         `json:"original_process"`
+	Source              string                         `json:"source"` // json-ld / microdata
+	Ingredients         []ImportedIngredient           `json:"ingredients"`
+	Missing             []string                       `json:"missing"` // カタログに無い材料名（重複なし）
+	Suggestions         map[string][]catalog.Candidate `json:"suggestions"`
+	LineErrors          []recipetext.LineError         `json:"line_errors"`
+	ExistingID          int                            `json:"existing_id,omitempty"` // 同じ URL か同じ名前のレシピが登録済み（ゴミ箱を除く）ならその id
+}
+
+// POST /api/recipes/import_url {"url": "https://..."}
+// レシピサイトのページから schema.org の Recipe（JSON-LD / microdata）を読み、保存前のプレビューを返す。
+// 材料は保存時と同じ recipetext と catalog で解決し、カタログに無いものは missing と suggestions に入れる。
+// 保存するときは name / yield / url / process / csv_data / original_* をそのまま POST /api/recipes に送る
+func handleRecipeImportURL(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		sendJSONError(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req struct {
+		URL string `json:"url"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	pageURL := strings.TrimSpace(req.URL)
+	u, err := url.Parse(pageURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		sendJSONError(w, "url は http:// か https:// で始まるURLを指定してください", http.StatusBadRequest)
+		return
+	}
+	if forbiddenImportHost(u.Hostname()) {
+		sendJSONError(w, errRecipeImportForbidden.Error()+": "+u.Host, http.StatusBadRequest)
+		return
+	}
+
+	recipe, err := recipeweb.Fetch(recipeImportClient(), pageURL)
+	if errors.Is(err, errRecipeImportForbidden) {
+		sendJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	} else if errors.Is(err, recipeweb.ErrNotFound) {
+		sendJSONError(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	} else if err != nil {
+		sendJSONError(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	preview, err := buildRecipeImportPreview(db, recipe)
+	if err != nil {
+		sendJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(preview)
+}
+
+func buildRecipeImportPreview(q catalog.Querier, recipe *recipeweb.Recipe) (*RecipeImportPreview, error) {
+	lines, lineErrors := recipetext.Parse(recipe.IngredientText())
+	if lineErrors == nil {
+		lineErrors = []recipetext.LineError{}
+	}
+	p := &RecipeImportPreview{
+		Name:                recipe.Name,
+		Yield:               recipe.Yield,
+		URL:                 recipe.URL,
+		Process:             recipe.ProcessText(),
+		CsvData:             recipetext.Format(lines),
+		OriginalIngredients: recipe.IngredientText(),
+		OriginalProcess:     recipe.ProcessText(),
+		Source:              recipe.Source,
+		Ingredients:         []ImportedIngredient{},
+		Missing:             []string{},
+		LineErrors:          lineErrors,
+	}
+
+	seen := make(map[string]bool)
+	for _, l := range lines {
+		ing := ImportedIngredient{Line: l}
+		match, err := catalog.Resolve(q, l.Name)
+		if err == nil {
+			ing.CatalogID, ing.CatalogName, ing.Alias = match.ID, match.Name, match.Alias
+			ing.Details = match.JoinDetails(l.Details)
+		} else if err != catalog.ErrNotFound {
+			return nil, err
+		} else if !seen[l.Name] {
+			seen[l.Name] = true
+			p.Missing = append(p.Missing, l.Name)
+		}
+		p.Ingredients = append(p.Ingredients, ing)
+	}
+
+	var err error
+	if p.Suggestions, err = suggestMissingItems(q, p.Missing); err != nil {
+		return nil, err
+	}
+
+	err = q.QueryRow("SELECT id FROM recipes WHERE (url = ? OR name = ?) AND deleted_at IS NULL ORDER BY url = ? DESC LIMIT 1",
+		p.URL, p.Name, p.URL).Scan(&p.ExistingID)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	return p, nil
+}
@@ -0,0 +1,433 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"kimichan/kana"
+)
+
+// レシピの全文検索。
+// FTS5 入り（go build -tags sqlite_fts5）でビルドしていれば recipe_fts 仮想テーブル（trigram）を引き、
+// FTS5 が無ければ全レシピを読み込んで順に調べる。どちらも kana.Fold で畳み込んだ文字列同士で比べる。
+// 索引は recipe_search_dirty（トリガーで印が付く）を見て、検索の直前に変更分だけ作り直す
+
+const (
+	recipeSearchLimit   = 50
+	recipeSnippetRadius = 30 // 抜粋は最初の一致の前後この文字数
+	ftsMinTermLength    = 3  // trigram で MATCH できる最短の長さ。これより短い語は LIKE で探す
+)
+
+// 列ごとの重み。順番は recipe_fts の列（名前・材料・元の材料表記・作り方）と同じ
+var recipeSearchColumns = []string{"name", "ingredients", "original_ingredients", "process"}
+var recipeSearchWeights = []float64{10, 5, 2, 1}
+
+var (
+	recipeFTSEnabled bool
+	recipeSearchMu   sync.Mutex
+)
+
+type RecipeSearchResult struct {
+	ID            int     `json:"id"`
+	Name          string  `json:"name"`
+	Yield         string  `json:"yield"`
+	URL           string  `json:"url"`
+	Score         float64 `json:"score"`
+	NameHighlight string  `json:"name_highlight"` // 一致箇所を <mark> で囲んだ名前（HTML エスケープ済み）
+	Field         string  `json:"field"`          // 抜粋を取った列（名前にしか一致しなければ空）
+	Snippet       string  `json:"snippet"`        // 一致箇所の前後（HTML エスケープ済み）
+}
+
+// 索引に入れる1レシピ分の文字列（畳み込む前）
+type recipeSearchDoc struct {
+	ID    int
+	Yield string
+	URL   string
+	Text  [4]string // recipeSearchColumns の順
+	Kana  string    // 材料のよみがな。索引にだけ入れる
+}
+
+// *sql.DB と *sql.Tx のどちらからでも引けるようにするためのインターフェース
+type queryer interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// 起動時に FTS5 の索引を用意する。FTS5 が無いビルドでは警告だけ出して順に調べる方式にする
+func initRecipeSearch() error {
+	_, err := db.Exec(`CREATE VIRTUAL TABLE IF NOT EXISTS recipe_fts
+		USING fts5(name, ingredients, original_ingredients, process, tokenize='trigram')`)
+	if err != nil {
+		if strings.Contains(err.Error(), "no such module") {
+			fmt.Println("⚠️ FTS5 が使えないため、レシピ検索は全件を順に調べます（go build -tags sqlite_fts5 で有効になります）")
+			return nil
+		}
+		return err
+	}
+	recipeFTSEnabled = true
+	// FTS5 なしで動かしていた間の変更もここでまとめて反映する
+	return syncRecipeSearch()
+}
+
+// 印の付いたレシピを索引に入れ直す。ゴミ箱や削除済みのレシピは索引から外す
+func syncRecipeSearch() error {
+	if !recipeFTSEnabled {
+		return nil
+	}
+	recipeSearchMu.Lock()
+	defer recipeSearchMu.Unlock()
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	ids, err := selectIDs(tx, "SELECT recipe_id FROM recipe_search_dirty ORDER BY recipe_id")
+	if err != nil || len(ids) == 0 {
+		tx.Rollback()
+		return err
+	}
+	docs, err := loadRecipeSearchDocs(tx, ids)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	for _, id := range ids {
+		if _, err := tx.Exec("DELETE FROM recipe_fts WHERE rowid = ?", id); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if d, ok := docs[id]; ok {
+			cols := d.folded()
+			if _, err := tx.Exec("INSERT INTO recipe_fts (rowid, name, ingredients, original_ingredients, process) VALUES (?, ?, ?, ?, ?)",
+				id, cols[0], cols[1], cols[2], cols[3]); err != nil {
+				tx.Rollback()
+				return err
+			}
+		}
+		if _, err := tx.Exec("DELETE FROM recipe_search_dirty WHERE recipe_id = ?", id); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// GET /api/recipes/search?q=たまねぎ 豚肉&limit=20
+// 空白区切りの語をすべて含むレシピを、名前 > 材料 > 元の材料表記 > 作り方 の重みで関連度の高い順に返す。
+// ひらがな・カタカナ、全角・半角、よくある漢字表記（玉ねぎ・玉葱）の違いは無視する
+func handleRecipeSearch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		sendJSONError(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	q := r.URL.Query()
+	terms := strings.Fields(kana.Fold(q.Get("q")))
+	if len(terms) == 0 {
+		sendJSONError(w, "q を指定してください", http.StatusBadRequest)
+		return
+	}
+	limit := recipeSearchLimit
+	if s := q.Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 {
+			sendJSONError(w, "limit は 0 以上の数値で指定してください", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	results, err := searchRecipes(terms, limit)
+	if err != nil {
+		sendJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(results)
+}
+
+// terms は畳み込み済みの語。limit が 0 なら件数の制限なし
+func searchRecipes(terms []string, limit int) ([]RecipeSearchResult, error) {
+	if err := syncRecipeSearch(); err != nil {
+		return nil, err
+	}
+
+	scores := make(map[int]float64)
+	var ids []int
+	ranked := false
+	if recipeFTSEnabled {
+		var err error
+		ids, scores, ranked, err = matchRecipeFTS(terms)
+		if err != nil {
+			return nil, err
+		}
+		if len(ids) == 0 {
+			return []RecipeSearchResult{}, nil
+		}
+	}
+	docs, err := loadRecipeSearchDocs(db, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	// FTS5 の bm25 が使えないとき（短い語だけ、または FTS5 なし）は一致した回数に重みを掛けて数える
+	if !ranked {
+		ids = ids[:0]
+		for id, d := range docs {
+			if score, ok := scoreRecipeDoc(d.folded(), terms); ok {
+				ids = append(ids, id)
+				scores[id] = score
+			}
+		}
+	}
+	sort.SliceStable(ids, func(i, j int) bool {
+		if scores[ids[i]] != scores[ids[j]] {
+			return scores[ids[i]] > scores[ids[j]]
+		}
+		return ids[i] > ids[j]
+	})
+	if limit > 0 && len(ids) > limit {
+		ids = ids[:limit]
+	}
+
+	results := []RecipeSearchResult{}
+	for _, id := range ids {
+		d, ok := docs[id]
+		if !ok {
+			continue
+		}
+		res := RecipeSearchResult{ID: id, Name: d.Text[0], Yield: d.Yield, URL: d.URL, Score: roundAmount(scores[id])}
+		res.NameHighlight, _ = highlightMatches(d.Text[0], terms, 0)
+		for i := 1; i < len(recipeSearchColumns); i++ {
+			if snippet, ok := highlightMatches(d.Text[i], terms, recipeSnippetRadius); ok {
+				res.Field = recipeSearchColumns[i]
+				res.Snippet = snippet
+				break
+			}
+		}
+		if res.Field == "" {
+			res.Snippet, _ = highlightMatches(d.Text[3], nil, recipeSnippetRadius)
+		}
+		results = append(results, res)
+	}
+	return results, nil
+}
+
+// recipe_fts から語をすべて含む行を探す。3文字以上の語があれば MATCH して bm25 の順に並べ（ranked = true）、
+// 短い語しか無ければ LIKE で絞り込むだけにする
+func matchRecipeFTS(terms []string) ([]int, map[int]float64, bool, error) {
+	var phrases, conds []string
+	var args []interface{}
+	for _, t := range terms {
+		if len([]rune(t)) >= ftsMinTermLength {
+			phrases = append(phrases, `"`+strings.ReplaceAll(t, `"`, `""`)+`"`)
+			continue
+		}
+		like := "%" + strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(t) + "%"
+		var ors []string
+		for _, col := range recipeSearchColumns {
+			ors = append(ors, col+` LIKE ? ESCAPE '\'`)
+			args = append(args, like)
+		}
+		conds = append(conds, "("+strings.Join(ors, " OR ")+")")
+	}
+
+	query := "SELECT rowid, 0 FROM recipe_fts"
+	ranked := len(phrases) > 0
+	if ranked {
+		weights := make([]string, len(recipeSearchWeights))
+		for i, w := range recipeSearchWeights {
+			weights[i] = strconv.FormatFloat(w, 'f', -1, 64)
+		}
+		query = "SELECT rowid, bm25(recipe_fts, " + strings.Join(weights, ", ") + ") FROM recipe_fts"
+		conds = append([]string{"recipe_fts MATCH ?"}, conds...)
+		args = append([]interface{}{strings.Join(phrases, " ")}, args...)
+	}
+	query += " WHERE " + strings.Join(conds, " AND ")
+	if ranked {
+		query += " ORDER BY 2"
+	}
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	defer rows.Close()
+
+	ids := []int{}
+	scores := make(map[int]float64)
+	for rows.Next() {
+		var id int
+		var rank float64
+		if err := rows.Scan(&id, &rank); err != nil {
+			return nil, nil, false, err
+		}
+		ids = append(ids, id)
+		scores[id] = -rank // bm25 は関連度が高いほど小さい（負の）値
+	}
+	return ids, scores, ranked, rows.Err()
+}
+
+// 索引に入れる文字列をレシピごとに読み込む。ids が nil なら全件。ゴミ箱のレシピは含めない
+func loadRecipeSearchDocs(q queryer, ids []int) (map[int]*recipeSearchDoc, error) {
+	where := ""
+	args := make([]interface{}, len(ids))
+	if ids != nil {
+		placeholders := make([]string, len(ids))
+		for i, id := range ids {
+			args[i] = id
+			placeholders[i] = "?"
+		}
+		where = " AND r.id IN (" + strings.Join(placeholders, ",") + ")"
+	}
+
+	rows, err := q.Query(`SELECT r.id, COALESCE(r.name, ''), COALESCE(r.yield, ''), COALESCE(r.url, ''),
+			COALESCE(r.original_ingredients, ''), COALESCE(r.process, '')
+		FROM recipes r WHERE r.deleted_at IS NULL`+where, args...)
+	if err != nil {
+		return nil, err
+	}
+	docs := make(map[int]*recipeSearchDoc)
+	for rows.Next() {
+		var d recipeSearchDoc
+		if err := rows.Scan(&d.ID, &d.Text[0], &d.Yield, &d.URL, &d.Text[2], &d.Text[3]); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		docs[d.ID] = &d
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = q.Query(`SELECT ri.recipe_id, c.name, COALESCE(c.kana, '')
+		FROM recipe_ingredients ri
+		JOIN item_catalog c ON ri.catalog_id = c.id
+		JOIN recipes r ON ri.recipe_id = r.id
+		WHERE r.deleted_at IS NULL`+where+`
+		ORDER BY ri.id`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var recipeID int
+		var name, yomi string
+		if err := rows.Scan(&recipeID, &name, &yomi); err != nil {
+			return nil, err
+		}
+		d, ok := docs[recipeID]
+		if !ok {
+			continue
+		}
+		if d.Text[1] != "" {
+			d.Text[1] += "、"
+		}
+		d.Text[1] += name
+		d.Kana += " " + yomi
+	}
+	return docs, rows.Err()
+}
+
+// 索引に入れる形（recipe_fts の列の順）
+func (d *recipeSearchDoc) folded() [4]string {
+	var cols [4]string
+	for i, t := range d.Text {
+		cols[i] = kana.Fold(t)
+	}
+	cols[1] += kana.Fold(d.Kana)
+	return cols
+}
+
+// 語ごとに一致した回数へ列の重みを掛けて足す。含まれない語が1つでもあれば false
+func scoreRecipeDoc(cols [4]string, terms []string) (float64, bool) {
+	score := 0.0
+	for _, t := range terms {
+		found := false
+		for i, col := range cols {
+			if n := strings.Count(col, t); n > 0 {
+				score += float64(n) * recipeSearchWeights[i]
+				found = true
+			}
+		}
+		if !found {
+			return 0, false
+		}
+	}
+	return score, true
+}
+
+// text の中で terms（畳み込み済み）に一致する箇所を <mark> で囲み、HTML エスケープして返す。
+// radius > 0 なら最初の一致の前後 radius 文字だけを切り出す（一致が無ければ先頭から）。
+// 2つ目の戻り値は一致があったかどうか
+func highlightMatches(text string, terms []string, radius int) (string, bool) {
+	orig := []rune(text)
+	folded, src := kana.FoldMap(text)
+	frunes := []rune(folded)
+
+	marked := make([]bool, len(orig))
+	first := -1
+	for _, t := range terms {
+		trunes := []rune(t)
+		for i := 0; i+len(trunes) <= len(frunes); i++ {
+			if string(frunes[i:i+len(trunes)]) != t {
+				continue
+			}
+			// 畳み込みで文字数が変わる（玉ねぎ → たまねぎ、ｶﾞ → が）ので元の文字の範囲に戻す
+			start, end := src[i], len(orig)
+			last := src[i+len(trunes)-1]
+			for k := i + len(trunes); k < len(src); k++ {
+				if src[k] > last {
+					end = src[k]
+					break
+				}
+			}
+			for k := start; k < end; k++ {
+				marked[k] = true
+			}
+			if first < 0 || start < first {
+				first = start
+			}
+		}
+	}
+
+	from, to := 0, len(orig)
+	if radius > 0 {
+		from = max(first-radius, 0)
+		to = min(from+radius*2, len(orig))
+	}
+
+	var b strings.Builder
+	if from > 0 {
+		b.WriteString("…")
+	}
+	open := false
+	for k := from; k < to; k++ {
+		if marked[k] != open {
+			if marked[k] {
+				b.WriteString("<mark>")
+			} else {
+				b.WriteString("</mark>")
+			}
+			open = marked[k]
+		}
+		r := orig[k]
+		if r == '\n' || r == '\r' || r == '\t' {
+			r = ' '
+		}
+		b.WriteString(html.EscapeString(string(r)))
+	}
+	if open {
+		b.WriteString("</mark>")
+	}
+	if to < len(orig) {
+		b.WriteString("…")
+	}
+	return b.String(), first >= 0
+}
@@ -0,0 +1,282 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 期限が近い在庫ほど重みを上げる。expiryWeightDays 日以上先なら上乗せなし、
+// 今日（期限切れを含む）なら expiryWeightMax を上乗せする
+const (
+	expiryWeightDays = 7
+	expiryWeightMax  = 2.0
+)
+
+type SuggestExpiring struct {
+	CatalogID int    `json:"catalog_id"`
+	Name      string `json:"name"`
+	DaysLeft  int    `json:"days_left"`
+}
+
+type RecipeSuggestion struct {
+	ID           int               `json:"id"`
+	Name         string            `json:"name"`
+	Yield        string            `json:"yield"`
+	URL          string            `json:"url"`
+	Score        float64           `json:"score"`
+	InStockCount int               `json:"in_stock_count"` // 在庫のある食材（調味料を除く）
+	MissingCount int               `json:"missing_count"`  // 在庫の無い食材（調味料を除く）
+	TotalCount   int               `json:"total_count"`
+	Availability string            `json:"availability"`
+	Missing      []string          `json:"missing"`
+	Expiring     []SuggestExpiring `json:"expiring"` // 使える在庫のうち期限が近いもの
+}
+
+// GET /api/recipes/suggest?max_missing=1&require=3,5&exclude=8&limit=20
+// 調味料以外の材料のうち在庫にあるものを数え、期限が近い在庫ほど重くしてレシピを並べる。
+//   - max_missing: 在庫の無い食材がこの数を超えるレシピは除外（省略時は制限なし）
+//   - require:     すべて使うレシピだけに絞る catalog_id（カンマ区切り）
+//   - exclude:     1つでも使うレシピを除外する catalog_id（カンマ区切り）
+func handleRecipeSuggest(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		sendJSONError(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	q := r.URL.Query()
+
+	maxMissing := -1
+	if s := q.Get("max_missing"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 {
+			sendJSONError(w, "max_missing は 0 以上の数値で指定してください", http.StatusBadRequest)
+			return
+		}
+		maxMissing = n
+	}
+	limit := 0
+	if s := q.Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 {
+			sendJSONError(w, "limit は 0 以上の数値で指定してください", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+	required, err := parseIDList(q.Get("require"))
+	if err != nil {
+		sendJSONError(w, "require: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	excluded, err := parseIDList(q.Get("exclude"))
+	if err != nil {
+		sendJSONError(w, "exclude: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	suggestions, err := suggestRecipes(time.Now(), maxMissing, required, excluded)
+	if err != nil {
+		sendJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if limit > 0 && len(suggestions) > limit {
+		suggestions = suggestions[:limit]
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(suggestions)
+}
+
+func suggestRecipes(now time.Time, maxMissing int, required, excluded []int) ([]RecipeSuggestion, error) {
+	recipeRows, err := db.Query("SELECT id, name, yield, url FROM recipes WHERE deleted_at IS NULL ORDER BY created_at DESC")
+	if err != nil {
+		return nil, err
+	}
+	var recipes []RecipeSuggestion
+	for recipeRows.Next() {
+		var s RecipeSuggestion
+		var yield, url sql.NullString
+		if err := recipeRows.Scan(&s.ID, &s.Name, &yield, &url); err != nil {
+			recipeRows.Close()
+			return nil, err
+		}
+		s.Yield = yield.String
+		s.URL = url.String
+		recipes = append(recipes, s)
+	}
+	recipeRows.Close()
+
+	type ingInfo struct {
+		CatalogID      int
+		Name           string
+		Classification string
+		Availability   IngredientAvailability
+	}
+	stock, err := loadInventoryStock()
+	if err != nil {
+		return nil, err
+	}
+	ingRows, err := db.Query(`
+		SELECT ri.recipe_id, ri.catalog_id, c.name, c.classification,
+			ri.quantity_value, ri.quantity_max, ri.quantity_unit, ri.quantity_qualifier
+		FROM recipe_ingredients ri
+		JOIN item_catalog c ON ri.catalog_id = c.id
+		ORDER BY ri.id ASC`)
+	if err != nil {
+		return nil, err
+	}
+	byRecipe := make(map[int][]ingInfo)
+	for ingRows.Next() {
+		var recipeID int
+		var ing ingInfo
+		var qv, qm sql.NullFloat64
+		var qu, qq sql.NullString
+		if err := ingRows.Scan(&recipeID, &ing.CatalogID, &ing.Name, &ing.Classification, &qv, &qm, &qu, &qq); err != nil {
+			ingRows.Close()
+			return nil, err
+		}
+		ing.Availability = stock.check(ing.CatalogID, ing.Classification, quantityFromColumns(qv, qm, qu, qq))
+		byRecipe[recipeID] = append(byRecipe[recipeID], ing)
+	}
+	ingRows.Close()
+
+	daysLeft, err := loadEarliestExpiry(now)
+	if err != nil {
+		return nil, err
+	}
+
+	suggestions := []RecipeSuggestion{}
+	for _, s := range recipes {
+		ings := byRecipe[s.ID]
+		uses := make(map[int]bool)
+		for _, ing := range ings {
+			uses[ing.CatalogID] = true
+		}
+		if !containsAll(uses, required) || containsAny(uses, excluded) {
+			continue
+		}
+
+		results := make([]IngredientAvailability, 0, len(ings))
+		counted := make(map[int]bool)
+		s.Missing = []string{}
+		s.Expiring = []SuggestExpiring{}
+		for _, ing := range ings {
+			results = append(results, ing.Availability)
+			// 同じ食材がグループ違いで2回出てきても1つと数える
+			if ing.Classification == "調味料" || counted[ing.CatalogID] {
+				continue
+			}
+			counted[ing.CatalogID] = true
+			s.TotalCount++
+
+			if ing.Availability.Status == StockMissing {
+				s.MissingCount++
+				s.Missing = append(s.Missing, ing.Name)
+				continue
+			}
+			s.InStockCount++
+			s.Score += 1
+			if d, ok := daysLeft[ing.CatalogID]; ok {
+				s.Score += expiryWeight(d)
+				if d < expiryWeightDays {
+					s.Expiring = append(s.Expiring, SuggestExpiring{CatalogID: ing.CatalogID, Name: ing.Name, DaysLeft: d})
+				}
+			}
+		}
+		if s.TotalCount == 0 {
+			continue
+		}
+		if maxMissing >= 0 && s.MissingCount > maxMissing {
+			continue
+		}
+		s.Score = roundAmount(s.Score)
+		s.Availability = recipeAvailability(results)
+		suggestions = append(suggestions, s)
+	}
+
+	// スコアが同じなら足りないものが少ない順
+	sort.SliceStable(suggestions, func(i, j int) bool {
+		if suggestions[i].Score != suggestions[j].Score {
+			return suggestions[i].Score > suggestions[j].Score
+		}
+		return suggestions[i].MissingCount < suggestions[j].MissingCount
+	})
+	return suggestions, nil
+}
+
+// 食材ごとの一番早い賞味期限までの日数（期限なしの在庫は含めない）
+func loadEarliestExpiry(now time.Time) (map[int]int, error) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	rows, err := db.Query(`
+		SELECT catalog_id, MIN(substr(expiration_date, 1, 10))
+		FROM refrigerator_ingredients
+		WHERE expiration_date GLOB '[0-9][0-9][0-9][0-9]-[0-9][0-9]-[0-9][0-9]*'
+		GROUP BY catalog_id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	days := make(map[int]int)
+	for rows.Next() {
+		var cid int
+		var date string
+		if err := rows.Scan(&cid, &date); err != nil {
+			return nil, err
+		}
+		if exp, err := time.Parse("2006-01-02", date); err == nil {
+			days[cid] = int(exp.Sub(today).Hours() / 24)
+		}
+	}
+	return days, rows.Err()
+}
+
+func expiryWeight(daysLeft int) float64 {
+	if daysLeft >= expiryWeightDays {
+		return 0
+	}
+	if daysLeft < 0 {
+		daysLeft = 0
+	}
+	return expiryWeightMax * float64(expiryWeightDays-daysLeft) / expiryWeightDays
+}
+
+// "3,5, 8" → [3 5 8]（空文字は nil）
+func parseIDList(s string) ([]int, error) {
+	var ids []int
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		id, err := strconv.Atoi(part)
+		if err != nil {
+			return nil, fmt.Errorf("不正な id です: %s", part)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+func containsAll(set map[int]bool, ids []int) bool {
+	for _, id := range ids {
+		if !set[id] {
+			return false
+		}
+	}
+	return true
+}
+
+func containsAny(set map[int]bool, ids []int) bool {
+	for _, id := range ids {
+		if set[id] {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestExpiryWeight(t *testing.T) {
+	tests := []struct {
+		daysLeft int
+		want     float64
+	}{
+		{-3, 2}, // 期限切れは今日と同じ
+		{0, 2},
+		{1, 2.0 * 6 / 7},
+		{6, 2.0 / 7},
+		{7, 0},
+		{30, 0},
+	}
+	for _, tt := range tests {
+		if got := expiryWeight(tt.daysLeft); got != tt.want {
+			t.Errorf("expiryWeight(%d) = %v, want %v", tt.daysLeft, got, tt.want)
+		}
+	}
+}
+
+func TestParseIDList(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    []int
+		wantErr bool
+	}{
+		{"", nil, false},
+		{"3,5, 8", []int{3, 5, 8}, false},
+		{"3,,5,", []int{3, 5}, false},
+		{"3,x", nil, true},
+	}
+	for _, tt := range tests {
+		got, err := parseIDList(tt.in)
+		if (err != nil) != tt.wantErr || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseIDList(%q) = %v, %v, want %v (error %v)", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestSuggestRecipes(t *testing.T) {
+	newTestDB(t)
+	catalogID := func(name, classification string) int {
+		return mustInsert(t, "INSERT INTO item_catalog(name, classification) VALUES(?, ?)", name, classification)
+	}
+	onion := catalogID("玉ねぎ", "食材")
+	pork := catalogID("豚肉", "食材")
+	egg := catalogID("卵", "食材")
+	soy := catalogID("醤油", "調味料")
+	mirin := catalogID("みりん", "調味料")
+
+	// 玉ねぎは明日が期限、豚肉は期限まで余裕あり、卵は在庫なし。みりんは切らしている
+	stock := func(cid int, amount float64, unit, expiration string) {
+		mustInsert(t, `INSERT INTO refrigerator_ingredients(catalog_id, amount, unit, expiration_date, location_id)
+			VALUES(?, ?, ?, ?, (SELECT id FROM locations WHERE name = '冷蔵庫'))`, cid, amount, unit, expiration)
+	}
+	stock(onion, 2, "個", "2026-10-19")
+	stock(pork, 300, "g", "2026-11-30")
+	mustInsert(t, "INSERT INTO refrigerator_seasonings(catalog_id, status) VALUES(?, ?)", soy, SeasoningStatusInStock)
+	mustInsert(t, "INSERT INTO refrigerator_seasonings(catalog_id, status) VALUES(?, ?)", mirin, SeasoningStatusOut)
+
+	recipe := func(name string, deleted bool, ings ...int) int {
+		id := mustInsert(t, "INSERT INTO recipes(name, yield) VALUES(?, '2人分')", name)
+		if deleted {
+			if _, err := db.Exec("UPDATE recipes SET deleted_at = CURRENT_TIMESTAMP WHERE id = ?", id); err != nil {
+				t.Fatal(err)
+			}
+		}
+		for _, cid := range ings {
+			mustInsert(t, "INSERT INTO recipe_ingredients(recipe_id, catalog_id, amount) VALUES(?, ?, '')", id, cid)
+		}
+		return id
+	}
+	gingerPork := recipe("生姜焼き", false, pork, onion, soy, mirin)
+	oyakodon := recipe("親子丼", false, onion, egg, soy, onion) // 玉ねぎはグループ違いで2回
+	omelette := recipe("卵焼き", false, egg, soy)
+	recipe("醤油だけ", false, soy)    // 調味料しか無いレシピは出さない
+	recipe("ゴミ箱のレシピ", true, pork) // ゴミ箱のレシピは出さない
+
+	now := time.Date(2026, 10, 18, 21, 0, 0, 0, time.Local)
+	tests := []struct {
+		name               string
+		maxMissing         int
+		required, excluded []int
+		want               []int
+	}{
+		{"期限の近い在庫を使うレシピが先", -1, nil, nil, []int{gingerPork, oyakodon, omelette}},
+		{"足りない食材の数で絞る", 0, nil, nil, []int{gingerPork}},
+		{"必ず使う食材", -1, []int{egg}, nil, []int{oyakodon, omelette}},
+		{"使わない食材", -1, nil, []int{pork}, []int{oyakodon, omelette}},
+		{"調味料でも絞れる", -1, []int{mirin}, nil, []int{gingerPork}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := suggestRecipes(now, tt.maxMissing, tt.required, tt.excluded)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ids := []int{}
+			for _, s := range got {
+				ids = append(ids, s.ID)
+			}
+			if !reflect.DeepEqual(ids, tt.want) {
+				t.Errorf("suggestRecipes ids = %v, want %v", ids, tt.want)
+			}
+		})
+	}
+
+	got, err := suggestRecipes(now, -1, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 生姜焼き: 豚肉 1 + 玉ねぎ 1 + 期限1日前の重み 12/7。調味料は数えない
+	s := got[0]
+	if s.Score != roundAmount(2+2.0*6/7) || s.InStockCount != 2 || s.MissingCount != 0 || s.TotalCount != 2 {
+		t.Errorf("生姜焼き = score %v, in stock %d, missing %d, total %d", s.Score, s.InStockCount, s.MissingCount, s.TotalCount)
+	}
+	if want := []SuggestExpiring{{CatalogID: onion, Name: "玉ねぎ", DaysLeft: 1}}; !reflect.DeepEqual(s.Expiring, want) {
+		t.Errorf("生姜焼き expiring = %v, want %v", s.Expiring, want)
+	}
+	if s.Availability != RecipePartiallyMakeable {
+		t.Errorf("生姜焼き availability = %s, want %s（みりんを切らしている）", s.Availability, RecipePartiallyMakeable)
+	}
+	s = got[1]
+	if s.TotalCount != 2 || s.MissingCount != 1 || !reflect.DeepEqual(s.Missing, []string{"卵"}) {
+		t.Errorf("親子丼 = total %d, missing %d %v", s.TotalCount, s.MissingCount, s.Missing)
+	}
+}
+
+func TestHandleRecipeSuggestBadRequest(t *testing.T) {
+	for _, query := range []string{"max_missing=-1", "limit=x", "require=1,a", "exclude=b"} {
+		rec := httptest.NewRecorder()
+		handleRecipeSuggest(rec, httptest.NewRequest("GET", "/api/recipes/suggest?"+query, nil))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("GET ?%s = %d, want %d", query, rec.Code, http.StatusBadRequest)
+		}
+	}
+}
@@ -1,15 +1,22 @@
 package main
 
 import (
-	"bufio"
 	"database/sql"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"os"
+	"strconv"
 	"strings"
+
+	"kimichan/catalog"
+	"kimichan/quantity"
+	"kimichan/recipetext"
 )
 
+// 見つからなかった材料ごとに返す近いカタログ項目の数
+const missingSuggestionLimit = 5
+
 type RecipeRequest struct {
 	Name                string `json:"name"`
 	Yield               string `json:"yield"`
@@ -18,12 +25,23 @@ type RecipeRequest struct {
 	URL                 string `json:"url"`
 	CsvData             string `json:"csv_data"`
 	OriginalIngredients string `json:"original_ingredients"`
+	// 指定するとカタログに無い材料をこの分類で登録してから保存する（省略時は missing_ingredients エラー）
+	CreateMissing *MissingItemOptions `json:"create_missing,omitempty"`
+}
+
+type MissingItemOptions struct {
+	Classification string `json:"classification"` // 食材 / 調味料（省略時は食材）
+	Category       string `json:"category"`       // 省略時は「その他」（調味料はカテゴリなし）
 }
 
 type RecipeResponse struct {
 	Recipe
 	HasIngredients bool `json:"has_ingredients"`
 	HasSeasonings  bool `json:"has_seasonings"`
+	// makeable / partially_makeable / unknown_units（必要量と在庫量を比べた結果）
+	Availability string `json:"availability"`
+	// yield から読み取った人数（"2人分" → 2）。読めなければ省略
+	Servings float64 `json:"servings,omitempty"`
 }
 
 func handleRecipes(w http.ResponseWriter, r *http.Request) {
@@ -34,6 +52,8 @@ func handleRecipes(w http.ResponseWriter, r *http.Request) {
 		addRecipe(w, r)
 	case "PUT":
 		updateRecipe(w, r)
+	case "DELETE":
+		deleteRecipes(w, r)
 	default:
 		sendJSONError(w, "Method Not Allowed", http.StatusMethodNotAllowed)
 	}
@@ -45,12 +65,15 @@ func sendJSONError(w http.ResponseWriter, message string, code int) {
 	json.NewEncoder(w).Encode(map[string]string{"error": message})
 }
 
-func sendMissingIngredientsError(w http.ResponseWriter, items []string) {
+// suggestions は見つからなかった名前ごとの近いカタログ項目、lineErrors は読めなかった行
+func sendMissingIngredientsError(w http.ResponseWriter, items []string, suggestions map[string][]catalog.Candidate, lineErrors []recipetext.LineError) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusBadRequest)
 	json.NewEncoder(w).Encode(map[string]interface{}{
-		"error_code": "missing_ingredients",
-		"items":      items,
+		"error_code":  "missing_ingredients",
+		"items":       items,
+		"suggestions": suggestions,
+		"line_errors": lineErrors,
 	})
 }
 
@@ -62,10 +85,10 @@ func getRecipes(w http.ResponseWriter, r *http.Request) {
 	var args []interface{}
 
 	if filterIngredientID != "" {
-		query = `SELECT r.id, r.name, r.yield, r.process, r.original_process, r.url, r.created_at, r.original_ingredients FROM recipes r JOIN recipe_ingredients ri ON r.id = ri.recipe_id WHERE ri.catalog_id = ? ORDER BY r.created_at DESC`
+		query = `SELECT r.id, r.name, r.yield, r.process, r.original_process, r.url, r.created_at, r.original_ingredients FROM recipes r JOIN recipe_ingredients ri ON r.id = ri.recipe_id WHERE ri.catalog_id = ? AND r.deleted_at IS NULL ORDER BY r.created_at DESC`
 		args = append(args, filterIngredientID)
 	} else {
-		query = `SELECT id, name, yield, process, original_process, url, created_at, original_ingredients FROM recipes ORDER BY created_at DESC`
+		query = `SELECT id, name, yield, process, original_process, url, created_at, original_ingredients FROM recipes WHERE deleted_at IS NULL ORDER BY created_at DESC`
 	}
 
 	isCloud := os.Getenv("K_SERVICE") != ""
@@ -88,6 +111,7 @@ func getRecipes(w http.ResponseWriter, r *http.Request) {
 			continue
 		}
 		r.Yield = yield.String
+		r.Servings, _ = quantity.ParseServings(r.Yield)
 		r.OriginalProcess = origProc.String
 		r.OriginalIngredients = origIng.String
 		recipes = append(recipes, r)
@@ -107,7 +131,8 @@ func getRecipes(w http.ResponseWriter, r *http.Request) {
 	}
 
 	queryIng := fmt.Sprintf(`
-		SELECT ri.recipe_id, ri.catalog_id, c.classification 
+		SELECT ri.recipe_id, ri.catalog_id, c.classification,
+			ri.quantity_value, ri.quantity_max, ri.quantity_unit, ri.quantity_qualifier
 		FROM recipe_ingredients ri 
 		JOIN item_catalog c ON ri.catalog_id = c.id 
 		WHERE ri.recipe_id IN (%s)
@@ -123,26 +148,25 @@ func getRecipes(w http.ResponseWriter, r *http.Request) {
 	type IngInfo struct {
 		CatalogID      int
 		Classification string
+		Quantity       quantity.Quantity
 	}
 	recipeIngMap := make(map[int][]IngInfo)
 
 	for rowsIng.Next() {
 		var rID, cID int
 		var cls string
-		if err := rowsIng.Scan(&rID, &cID, &cls); err == nil {
-			recipeIngMap[rID] = append(recipeIngMap[rID], IngInfo{CatalogID: cID, Classification: cls})
+		var qv, qm sql.NullFloat64
+		var qu, qq sql.NullString
+		if err := rowsIng.Scan(&rID, &cID, &cls, &qv, &qm, &qu, &qq); err == nil {
+			recipeIngMap[rID] = append(recipeIngMap[rID], IngInfo{CatalogID: cID, Classification: cls, Quantity: quantityFromColumns(qv, qm, qu, qq)})
 		}
 	}
 
-	invMap := make(map[int]bool)
-	rowsInv, _ := db.Query("SELECT catalog_id FROM refrigerator_ingredients")
-	if rowsInv != nil {
-		for rowsInv.Next() {
-			var cid int
-			rowsInv.Scan(&cid)
-			invMap[cid] = true
-		}
-		rowsInv.Close()
+	// 在庫は量まで見て判定する（調味料は refrigerator_seasonings のステータスで判定）
+	stock, err := loadInventoryStock()
+	if err != nil {
+		sendJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
 	}
 
 	for i := range recipes {
@@ -150,20 +174,22 @@ func getRecipes(w http.ResponseWriter, r *http.Request) {
 		hasSeas := true
 		ingredients := recipeIngMap[recipes[i].ID]
 
+		results := make([]IngredientAvailability, 0, len(ingredients))
 		for _, ing := range ingredients {
-			inStock := invMap[ing.CatalogID]
+			a := stock.check(ing.CatalogID, ing.Classification, ing.Quantity)
+			results = append(results, a)
+			if a.Status != StockShort && a.Status != StockMissing {
+				continue
+			}
 			if ing.Classification == "調味料" {
-				if !inStock {
-					hasSeas = false
-				}
+				hasSeas = false
 			} else {
-				if !inStock {
-					hasIng = false
-				}
+				hasIng = false
 			}
 		}
 		recipes[i].HasIngredients = hasIng
 		recipes[i].HasSeasonings = hasSeas
+		recipes[i].Availability = recipeAvailability(results)
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -195,6 +221,15 @@ func saveRecipeCommon(w http.ResponseWriter, r *http.Request, id int) {
 		sendJSONError(w, "レシピ名は必須です", http.StatusBadRequest)
 		return
 	}
+	if opts := req.CreateMissing; opts != nil {
+		if opts.Classification == "" {
+			opts.Classification = "食材"
+		}
+		if opts.Classification != "食材" && opts.Classification != "調味料" {
+			sendJSONError(w, "create_missing.classification は 食材 か 調味料 を指定してください", http.StatusBadRequest)
+			return
+		}
+	}
 
 	// 3列仕様: Amountに単位込み、Detailsを追加
 	type parsedIng struct {
@@ -206,6 +241,7 @@ func saveRecipeCommon(w http.ResponseWriter, r *http.Request, id int) {
 	}
 	var ingredients []parsedIng
 	var unknownItems []string
+	created := []catalog.Match{}
 
 	tx, err := db.Begin()
 	if err != nil {
@@ -213,74 +249,76 @@ func saveRecipeCommon(w http.ResponseWriter, r *http.Request, id int) {
 		return
 	}
 
-	// ★修正: 「/」を改行に変換する処理を削除しました
-	// そのまま req.CsvData を読み込みます
-	scanner := bufio.NewScanner(strings.NewReader(req.CsvData))
-	var currentGroup string
-
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-		if line == "" {
-			continue
-		}
-
-		if strings.HasPrefix(line, "=") || strings.HasPrefix(line, "＝") {
-			currentGroup = strings.Trim(line, "=＝ \t　")
-			continue
-		}
-
-		var name, amount, details string
+	// 書式は recipetext パッケージを参照。読めなかった行は保存せず、行番号付きで line_errors に返す
+	lines, lineErrors := recipetext.Parse(req.CsvData)
+	if lineErrors == nil {
+		lineErrors = []recipetext.LineError{}
+	}
 
-		if strings.Contains(line, ",") {
-			parts := strings.Split(line, ",")
-			name = strings.TrimSpace(parts[0])
-			// 2列目: 分量(単位込み)
-			if len(parts) > 1 {
-				amount = strings.TrimSpace(parts[1])
-			}
-			// 3列目: 詳細
-			if len(parts) > 2 {
-				details = strings.TrimSpace(parts[2])
-			}
-		} else if strings.Contains(line, "…") {
-			parts := strings.SplitN(line, "…", 2)
-			name = strings.TrimSpace(parts[0])
-			if len(parts) > 1 {
-				amount = strings.TrimSpace(parts[1])
+	for _, l := range lines {
+		name := l.Name
+		// 名前・よみがな・別名で探す。別名に既定の詳細（「粉末」など）があれば詳細の前に付ける
+		match, err := catalog.Resolve(tx, name)
+		if err == catalog.ErrNotFound && req.CreateMissing != nil {
+			// 同じ名前が2回出てきても、2回目は登録したものに当たる
+			if match, err = createMissingCatalogItem(tx, r, name, req.CreateMissing); err == nil {
+				created = append(created, *match)
 			}
-		} else if strings.Contains(line, "...") {
-			parts := strings.SplitN(line, "...", 2)
-			name = strings.TrimSpace(parts[0])
-			if len(parts) > 1 {
-				amount = strings.TrimSpace(parts[1])
-			}
-		} else {
-			name = line
-		}
-
-		if name == "" {
-			continue
 		}
-
-		var catalogID int
-		err = tx.QueryRow("SELECT id FROM item_catalog WHERE name = ? OR kana = ?", name, name).Scan(&catalogID)
-		if err != nil {
+		if err == catalog.ErrNotFound {
 			unknownItems = append(unknownItems, name)
 			continue
+		} else if err != nil {
+			tx.Rollback()
+			sendJSONError(w, err.Error(), http.StatusInternalServerError)
+			return
 		}
 
 		ingredients = append(ingredients, parsedIng{
-			CatalogID: catalogID,
+			CatalogID: match.ID,
 			Unit:      "", // 単位カラムは空にする
-			Amount:    amount,
-			GroupName: currentGroup,
-			Details:   details,
+			Amount:    l.Amount,
+			GroupName: l.Group,
+			Details:   match.JoinDetails(l.Details),
 		})
 	}
 
 	if len(unknownItems) > 0 {
+		suggestions, err := suggestMissingItems(tx, unknownItems)
+		if err != nil {
+			tx.Rollback()
+			sendJSONError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
 		tx.Rollback()
-		sendMissingIngredientsError(w, unknownItems)
+		sendMissingIngredientsError(w, unknownItems, suggestions, lineErrors)
+		return
+	}
+
+	var before map[string]interface{}
+	if id != 0 {
+		if before, err = snapshotRecipe(tx, id); err != nil {
+			tx.Rollback()
+			sendJSONError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if before == nil {
+			tx.Rollback()
+			sendJSONError(w, "not found", http.StatusNotFound)
+			return
+		}
+	}
+
+	// 名前は UNIQUE なので、ゴミ箱にある同名レシピとはぶつかる
+	var trashedID int
+	err = tx.QueryRow("SELECT id FROM recipes WHERE name = ? AND id != ? AND deleted_at IS NOT NULL", req.Name, id).Scan(&trashedID)
+	if err == nil {
+		tx.Rollback()
+		sendJSONError(w, fmt.Sprintf("ゴミ箱に同じ名前のレシピがあります（id=%d）。復元するか完全に削除してください", trashedID), http.StatusConflict)
+		return
+	} else if err != sql.ErrNoRows {
+		tx.Rollback()
+		sendJSONError(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
@@ -310,7 +348,8 @@ func saveRecipeCommon(w http.ResponseWriter, r *http.Request, id int) {
 		}
 	}
 
-	ingStmt, err := tx.Prepare("INSERT INTO recipe_ingredients(recipe_id, catalog_id, unit, amount, group_name, details) VALUES(?, ?, ?, ?, ?, ?)")
+	ingStmt, err := tx.Prepare(`INSERT INTO recipe_ingredients(recipe_id, catalog_id, unit, amount, group_name, details,
+		quantity_value, quantity_max, quantity_unit, quantity_qualifier) VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
 	if err != nil {
 		tx.Rollback()
 		sendJSONError(w, err.Error(), http.StatusInternalServerError)
@@ -319,13 +358,29 @@ func saveRecipeCommon(w http.ResponseWriter, r *http.Request, id int) {
 	defer ingStmt.Close()
 
 	for _, ing := range ingredients {
-		if _, err := ingStmt.Exec(id, ing.CatalogID, ing.Unit, ing.Amount, ing.GroupName, ing.Details); err != nil {
+		q, _ := quantity.Parse(ing.Amount)
+		if _, err := ingStmt.Exec(id, ing.CatalogID, ing.Unit, ing.Amount, ing.GroupName, ing.Details,
+			q.ValueOrNil(), q.MaxOrNil(), q.Unit, q.Qualifier); err != nil {
 			tx.Rollback()
 			sendJSONError(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
 	}
 
+	action := AuditCreate
+	if before != nil {
+		action = AuditUpdate
+	}
+	after, err := snapshotRecipe(tx, id)
+	if err == nil {
+		err = writeAudit(tx, r, AuditRecipe, id, action, before, after)
+	}
+	if err != nil {
+		tx.Rollback()
+		sendJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
 	if err := tx.Commit(); err != nil {
 		sendJSONError(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -333,9 +388,51 @@ func saveRecipeCommon(w http.ResponseWriter, r *http.Request, id int) {
 
 	w.WriteHeader(http.StatusOK)
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{"status": "success"})
+	json.NewEncoder(w).Encode(map[string]interface{}{"status": "success", "id": id, "created": created, "line_errors": lineErrors})
 }
 
+// カタログに見つからなかった名前ごとに、近いカタログ項目を missingSuggestionLimit 件まで探す
+func suggestMissingItems(q catalog.Querier, names []string) (map[string][]catalog.Candidate, error) {
+	suggestions := make(map[string][]catalog.Candidate)
+	for _, name := range names {
+		if _, ok := suggestions[name]; ok {
+			continue
+		}
+		candidates, err := catalog.Suggest(q, name, missingSuggestionLimit)
+		if err != nil {
+			return nil, err
+		}
+		suggestions[name] = candidates
+	}
+	return suggestions, nil
+}
+
+// カタログに無かった材料を登録する。食材は単位「個」・カテゴリ「その他」、調味料は単位・カテゴリなし
+func createMissingCatalogItem(tx *sql.Tx, r *http.Request, name string, opts *MissingItemOptions) (*catalog.Match, error) {
+	category, unit := opts.Category, "個"
+	if opts.Classification == "調味料" {
+		category, unit = "", ""
+	} else if category == "" {
+		category = "その他"
+	}
+	res, err := tx.Exec("INSERT INTO item_catalog(name, kana, classification, category, default_unit) VALUES(?, ?, ?, ?, ?)",
+		name, "", opts.Classification, category, unit)
+	if err != nil {
+		return nil, err
+	}
+	if err := auditCatalogUpsert(tx, r, name, nil); err != nil {
+		return nil, err
+	}
+	id, _ := res.LastInsertId()
+	return &catalog.Match{ID: int(id), Name: name}, nil
+}
+
+// 在庫数（調味料は「なし」以外のストック、それ以外は在庫の行数）。c は item_catalog の別名
+const stockCountSQL = `CASE WHEN c.classification = '調味料'
+				THEN (SELECT COUNT(*) FROM refrigerator_seasonings WHERE catalog_id = c.id AND status != 'なし')
+				ELSE (SELECT COUNT(*) FROM refrigerator_ingredients WHERE catalog_id = c.id)
+			END`
+
 func handleRecipeIngredients(w http.ResponseWriter, r *http.Request) {
 	if r.Method != "GET" {
 		sendJSONError(w, "Method Not Allowed", http.StatusMethodNotAllowed)
@@ -348,6 +445,31 @@ func handleRecipeIngredients(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// servings を指定すると、yield の人数との比で分量を増減して返す
+	scale := 1.0
+	if s := r.URL.Query().Get("servings"); s != "" {
+		servings, err := strconv.ParseFloat(s, 64)
+		if err != nil || servings <= 0 {
+			sendJSONError(w, "servings must be a positive number", http.StatusBadRequest)
+			return
+		}
+		var yield sql.NullString
+		err = db.QueryRow("SELECT yield FROM recipes WHERE id = ?", recipeID).Scan(&yield)
+		if err == sql.ErrNoRows {
+			sendJSONError(w, "recipe not found", http.StatusNotFound)
+			return
+		} else if err != nil {
+			sendJSONError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		base, ok := quantity.ParseServings(yield.String)
+		if !ok {
+			sendJSONError(w, "レシピの人数（yield）を読み取れないため人数を変えられません: "+yield.String, http.StatusBadRequest)
+			return
+		}
+		scale = servings / base
+	}
+
 	query := `
 		SELECT 
 			c.name, 
@@ -356,7 +478,8 @@ func handleRecipeIngredients(w http.ResponseWriter, r *http.Request) {
 			ri.group_name,
 			ri.details,
 			ri.catalog_id, 
-			(SELECT COUNT(*) FROM refrigerator_ingredients WHERE catalog_id = c.id) as stock_count
+			c.classification,
+			ri.quantity_value, ri.quantity_max, ri.quantity_unit, ri.quantity_qualifier
 		FROM recipe_ingredients ri
 		JOIN item_catalog c ON ri.catalog_id = c.id
 		WHERE ri.recipe_id = ?
@@ -370,6 +493,12 @@ func handleRecipeIngredients(w http.ResponseWriter, r *http.Request) {
 	}
 	defer rows.Close()
 
+	stock, err := loadInventoryStock()
+	if err != nil {
+		sendJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
 	type ResIngredient struct {
 		Name      string `json:"name"`
 		Amount    string `json:"amount"`
@@ -377,21 +506,33 @@ func handleRecipeIngredients(w http.ResponseWriter, r *http.Request) {
 		GroupName string `json:"group_name"`
 		Details   string `json:"details"`
 		CatalogID int    `json:"catalog_id"`
-		InStock   bool   `json:"in_stock"`
+		// servings 指定時は人数に合わせた分量（表示用の文字列と分解した値）
+		ScaledAmount string            `json:"scaled_amount"`
+		Quantity     quantity.Quantity `json:"quantity"`
+		// in_stock は在庫が1つでもあるか。量が足りるかは availability を見る
+		InStock      bool                   `json:"in_stock"`
+		Availability IngredientAvailability `json:"availability"`
 	}
 
 	var ingredients []ResIngredient
 	for rows.Next() {
 		var i ResIngredient
-		var stockCount int
+		var cls string
 		var gn, dt sql.NullString
-		if err := rows.Scan(&i.Name, &i.Amount, &i.Unit, &gn, &dt, &i.CatalogID, &stockCount); err != nil {
+		var qv, qm sql.NullFloat64
+		var qu, qq sql.NullString
+		if err := rows.Scan(&i.Name, &i.Amount, &i.Unit, &gn, &dt, &i.CatalogID, &cls, &qv, &qm, &qu, &qq); err != nil {
 			sendJSONError(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
 		i.GroupName = gn.String
 		i.Details = dt.String
-		i.InStock = (stockCount > 0)
+		qty := quantityFromColumns(qv, qm, qu, qq)
+		qty.Raw = i.Amount
+		i.Quantity = qty.Scale(scale)
+		i.ScaledAmount = i.Quantity.Raw
+		i.Availability = stock.check(i.CatalogID, cls, i.Quantity)
+		i.InStock = i.Availability.Status != StockMissing
 		ingredients = append(ingredients, i)
 	}
 
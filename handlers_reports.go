@@ -0,0 +1,103 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// 廃棄量の集計1行（単位が混在するので unit ごとに分ける）
+type WasteSummary struct {
+	Key    string  `json:"key"`
+	Unit   string  `json:"unit"`
+	Amount float64 `json:"amount"`
+	Count  int     `json:"count"`
+}
+
+// GET /api/reports/waste?since=2026-01-01&until=2026-04-01
+// 廃棄イベント（consumed 以外の理由で削除した在庫）をカテゴリ・場所・月・理由・品目別に集計する
+// 量不明（-1）の在庫は件数だけ数える
+func handleWasteReport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		sendJSONError(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	q := r.URL.Query()
+
+	where := "e.event_type = ?"
+	args := []interface{}{EventDiscarded}
+	if since := q.Get("since"); since != "" {
+		t, err := parseSince(since)
+		if err != nil {
+			sendJSONError(w, "since は YYYY-MM-DD または RFC3339 形式で指定してください", http.StatusBadRequest)
+			return
+		}
+		where += " AND e.created_at >= ?"
+		args = append(args, t.UTC().Format("2006-01-02 15:04:05"))
+	}
+	if until := q.Get("until"); until != "" {
+		t, err := parseSince(until)
+		if err != nil {
+			sendJSONError(w, "until は YYYY-MM-DD または RFC3339 形式で指定してください", http.StatusBadRequest)
+			return
+		}
+		where += " AND e.created_at < ?"
+		args = append(args, t.UTC().Format("2006-01-02 15:04:05"))
+	}
+
+	groups := []struct {
+		name string
+		key  string
+	}{
+		{"by_category", "COALESCE(NULLIF(c.category, ''), 'その他')"},
+		{"by_location", "COALESCE(l.name, '不明')"},
+		{"by_month", "strftime('%Y-%m', e.created_at)"},
+		{"by_reason", "COALESCE(e.reason, '')"},
+		{"by_item", "c.name"},
+	}
+
+	report := map[string]interface{}{}
+	total := 0
+	for _, g := range groups {
+		list, err := queryWasteSummary(g.key, where, args)
+		if err != nil {
+			sendJSONError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		report[g.name] = list
+		if g.name == "by_month" {
+			for _, s := range list {
+				total += s.Count
+			}
+		}
+	}
+	report["total_count"] = total
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+func queryWasteSummary(key, where string, args []interface{}) ([]WasteSummary, error) {
+	rows, err := db.Query(`
+		SELECT `+key+` AS k, COALESCE(e.unit, ''),
+			SUM(MAX(e.amount, 0)), COUNT(*)
+		FROM ingredient_events e
+		JOIN item_catalog c ON e.catalog_id = c.id
+		LEFT JOIN locations l ON e.location_id = l.id
+		WHERE `+where+`
+		GROUP BY k, COALESCE(e.unit, '')
+		ORDER BY COUNT(*) DESC, k ASC`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []WasteSummary{}
+	for rows.Next() {
+		var s WasteSummary
+		if err := rows.Scan(&s.Key, &s.Unit, &s.Amount, &s.Count); err != nil {
+			return nil, err
+		}
+		list = append(list, s)
+	}
+	return list, rows.Err()
+}
@@ -0,0 +1,331 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// 調味料のストック状態（seasoning_view.html と seasonings.html のプルダウンと合わせる）
+const (
+	SeasoningStatusInStock = "あり"
+	SeasoningStatusLow     = "少なめ"
+	SeasoningStatusOut     = "なし"
+)
+
+func isValidSeasoningStatus(status string) bool {
+	switch status {
+	case SeasoningStatusInStock, SeasoningStatusLow, SeasoningStatusOut:
+		return true
+	}
+	return false
+}
+
+func handleSeasonings(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		getSeasonings(w, r)
+	case "POST":
+		addSeasoning(w, r)
+	case "PUT":
+		updateSeasoning(w, r)
+	case "DELETE":
+		deleteSeasoning(w, r)
+	default:
+		sendJSONError(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func getSeasonings(w http.ResponseWriter, _ *http.Request) {
+	query := `
+		SELECT s.id, s.catalog_id, s.status, s.created_at, s.updated_at,
+			c.name, c.classification, c.category
+		FROM refrigerator_seasonings s
+		JOIN item_catalog c ON s.catalog_id = c.id
+		WHERE c.classification = '調味料'
+		ORDER BY COALESCE(NULLIF(c.kana, ''), c.name) ASC
+	`
+	rows, err := db.Query(query)
+	if err != nil {
+		sendJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	seasonings := []Seasoning{}
+	for rows.Next() {
+		var s Seasoning
+		var category sql.NullString
+		if err := rows.Scan(&s.ID, &s.CatalogID, &s.Status, &s.CreatedAt, &s.UpdatedAt,
+			&s.Name, &s.Classification, &category); err != nil {
+			sendJSONError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		s.Category = category.String
+		seasonings = append(seasonings, s)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(seasonings)
+}
+
+// 同じ catalog_id が既にあればステータスを上書きする
+func addSeasoning(w http.ResponseWriter, r *http.Request) {
+	var s Seasoning
+	if err := json.NewDecoder(r.Body).Decode(&s); err != nil {
+		sendJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if s.CatalogID == 0 {
+		sendJSONError(w, "catalog_id required", http.StatusBadRequest)
+		return
+	}
+	if s.Status == "" {
+		s.Status = SeasoningStatusInStock
+	}
+	if !isValidSeasoningStatus(s.Status) {
+		sendJSONError(w, "不正なステータスです: "+s.Status, http.StatusBadRequest)
+		return
+	}
+
+	var classification string
+	err := db.QueryRow("SELECT name, classification FROM item_catalog WHERE id = ?", s.CatalogID).Scan(&s.Name, &classification)
+	if err == sql.ErrNoRows {
+		sendJSONError(w, "カタログに存在しません", http.StatusBadRequest)
+		return
+	} else if err != nil {
+		sendJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if classification != "調味料" {
+		sendJSONError(w, fmt.Sprintf("「%s」は調味料ではありません", s.Name), http.StatusBadRequest)
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		sendJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	var existingID int
+	err = tx.QueryRow("SELECT id FROM refrigerator_seasonings WHERE catalog_id = ?", s.CatalogID).Scan(&existingID)
+	if err == nil {
+		s.ID = existingID
+		err = setSeasoningStatus(tx, r, s.ID, s.Status)
+	} else if err == sql.ErrNoRows {
+		s.ID, err = insertSeasoning(tx, r, s.CatalogID, s.Status)
+	}
+	if err != nil {
+		tx.Rollback()
+		sendJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		sendJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	s.Classification = classification
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(s)
+}
+
+func updateSeasoning(w http.ResponseWriter, r *http.Request) {
+	var s Seasoning
+	if err := json.NewDecoder(r.Body).Decode(&s); err != nil {
+		sendJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if s.ID == 0 {
+		sendJSONError(w, "id required", http.StatusBadRequest)
+		return
+	}
+	if !isValidSeasoningStatus(s.Status) {
+		sendJSONError(w, "不正なステータスです: "+s.Status, http.StatusBadRequest)
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		sendJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	err = setSeasoningStatus(tx, r, s.ID, s.Status)
+	if err == sql.ErrNoRows {
+		tx.Rollback()
+		sendJSONError(w, "not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		tx.Rollback()
+		sendJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		sendJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "updated"})
+}
+
+func deleteSeasoning(w http.ResponseWriter, r *http.Request) {
+	idStr := r.URL.Query().Get("id")
+	if idStr == "" {
+		sendJSONError(w, "id required", http.StatusBadRequest)
+		return
+	}
+	var id int
+	fmt.Sscanf(idStr, "%d", &id)
+
+	tx, err := db.Begin()
+	if err != nil {
+		sendJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	before, err := snapshotRow(tx, "refrigerator_seasonings", id)
+	if err != nil {
+		tx.Rollback()
+		sendJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if _, err := tx.Exec("DELETE FROM refrigerator_seasonings WHERE id = ?", id); err != nil {
+		tx.Rollback()
+		sendJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if before != nil {
+		if err := writeAudit(tx, r, AuditSeasoning, id, AuditDelete, before, nil); err != nil {
+			tx.Rollback()
+			sendJSONError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		sendJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "deleted"})
+}
+
+// カタログの調味料のうち、まだストック管理されていないものを一括登録する
+// POST /api/seasonings/init {"status": "あり"}  (status 省略時は「あり」)
+func handleSeasoningsInit(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		sendJSONError(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		Status string `json:"status"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			sendJSONError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	if req.Status == "" {
+		req.Status = SeasoningStatusInStock
+	}
+	if !isValidSeasoningStatus(req.Status) {
+		sendJSONError(w, "不正なステータスです: "+req.Status, http.StatusBadRequest)
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		sendJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	rows, err := tx.Query(`
+		SELECT c.id FROM item_catalog c
+		WHERE c.classification = '調味料'
+		  AND c.id NOT IN (SELECT catalog_id FROM refrigerator_seasonings)
+	`)
+	if err != nil {
+		tx.Rollback()
+		sendJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	var catalogIDs []int
+	for rows.Next() {
+		var cid int
+		rows.Scan(&cid)
+		catalogIDs = append(catalogIDs, cid)
+	}
+	rows.Close()
+
+	added := 0
+	for _, cid := range catalogIDs {
+		if _, err := insertSeasoning(tx, r, cid, req.Status); err != nil {
+			tx.Rollback()
+			sendJSONError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		added++
+	}
+	if err := tx.Commit(); err != nil {
+		sendJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"status": "success", "added": added})
+}
+
+func insertSeasoning(tx *sql.Tx, r *http.Request, catalogID int, status string) (int, error) {
+	res, err := tx.Exec("INSERT INTO refrigerator_seasonings(catalog_id, status) VALUES(?, ?)", catalogID, status)
+	if err != nil {
+		return 0, err
+	}
+	id64, _ := res.LastInsertId()
+	id := int(id64)
+	after, err := snapshotRow(tx, "refrigerator_seasonings", id)
+	if err != nil {
+		return 0, err
+	}
+	return id, writeAudit(tx, r, AuditSeasoning, id, AuditCreate, nil, after)
+}
+
+// 行が無ければ sql.ErrNoRows を返す
+func setSeasoningStatus(tx *sql.Tx, r *http.Request, id int, status string) error {
+	before, err := snapshotRow(tx, "refrigerator_seasonings", id)
+	if err != nil {
+		return err
+	}
+	if before == nil {
+		return sql.ErrNoRows
+	}
+	if _, err := tx.Exec("UPDATE refrigerator_seasonings SET status = ?, updated_at = datetime('now','localtime') WHERE id = ?", status, id); err != nil {
+		return err
+	}
+	after, err := snapshotRow(tx, "refrigerator_seasonings", id)
+	if err != nil {
+		return err
+	}
+	return writeAudit(tx, r, AuditSeasoning, id, AuditUpdate, before, after)
+}
+
+// 在庫ありとみなす調味料の catalog_id 集合（「なし」以外）
+func loadSeasoningStock() (map[int]bool, error) {
+	rows, err := db.Query("SELECT catalog_id FROM refrigerator_seasonings WHERE status != ?", SeasoningStatusOut)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stock := make(map[int]bool)
+	for rows.Next() {
+		var cid int
+		if err := rows.Scan(&cid); err != nil {
+			return nil, err
+		}
+		stock[cid] = true
+	}
+	return stock, rows.Err()
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// 場所の保存方法（locations.storage_type）
+const (
+	StorageFridge  = "fridge"
+	StorageFreezer = "freezer"
+	StorageRoom    = "room"
+)
+
+func isValidStorageType(s string) bool {
+	switch s {
+	case StorageFridge, StorageFreezer, StorageRoom:
+		return true
+	}
+	return false
+}
+
+// 保存方法ごとの item_catalog の日持ちカラム
+var shelfLifeColumns = map[string]string{
+	StorageFridge:  "shelf_life_fridge",
+	StorageFreezer: "shelf_life_freezer",
+	StorageRoom:    "shelf_life_room",
+}
+
+// 賞味期限を自動で決めた根拠。Source は "catalog"（項目ごとの設定）か "category"（カテゴリの目安）
+type ShelfLifeRule struct {
+	Source      string `json:"source"`
+	Category    string `json:"category,omitempty"`
+	StorageType string `json:"storage_type"`
+	Days        int    `json:"days"`
+}
+
+// カタログ項目の設定 → カテゴリの目安 の順に日持ち日数を探す。見つからなければ nil
+func lookupShelfLife(q queryRower, catalogID int, storageType string) (*ShelfLifeRule, error) {
+	col, ok := shelfLifeColumns[storageType]
+	if !ok {
+		return nil, nil
+	}
+
+	var days sql.NullInt64
+	var category string
+	err := q.QueryRow("SELECT "+col+", COALESCE(category, '') FROM item_catalog WHERE id = ?", catalogID).Scan(&days, &category)
+	if err != nil {
+		return nil, err
+	}
+	if days.Valid && days.Int64 > 0 {
+		return &ShelfLifeRule{Source: "catalog", StorageType: storageType, Days: int(days.Int64)}, nil
+	}
+
+	err = q.QueryRow("SELECT days FROM category_shelf_life WHERE category = ? AND storage_type = ?", category, storageType).Scan(&days)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return &ShelfLifeRule{Source: "category", Category: category, StorageType: storageType, Days: int(days.Int64)}, nil
+}
+
+// 賞味期限が空のとき、場所の保存方法と日持ち日数から埋める
+func fillExpirationDate(q queryRower, item *Ingredient, now time.Time) error {
+	if item.ExpirationDate != "" {
+		return nil
+	}
+	var storageType string
+	if err := q.QueryRow("SELECT storage_type FROM locations WHERE id = ?", item.LocationID).Scan(&storageType); err != nil {
+		return err
+	}
+	rule, err := lookupShelfLife(q, item.CatalogID, storageType)
+	if err != nil || rule == nil {
+		return err
+	}
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	item.ExpirationDate = today.AddDate(0, 0, rule.Days).Format(expirationLayout)
+	item.ExpirationRule = rule
+	return nil
+}
+
+type CategoryShelfLife struct {
+	Category    string `json:"category"`
+	StorageType string `json:"storage_type"`
+	Days        int    `json:"days"`
+}
+
+// GET /api/catalog/shelf_life                カテゴリごとの日持ちの目安一覧
+// PUT /api/catalog/shelf_life {"category": "野菜", "storage_type": "fridge", "days": 7}
+//
+//	days が 0 以下なら目安を削除する
+func handleCategoryShelfLife(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		rows, err := db.Query("SELECT category, storage_type, days FROM category_shelf_life ORDER BY category ASC, storage_type ASC")
+		if err != nil {
+			sendJSONError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		defer rows.Close()
+
+		list := []CategoryShelfLife{}
+		for rows.Next() {
+			var c CategoryShelfLife
+			if err := rows.Scan(&c.Category, &c.StorageType, &c.Days); err != nil {
+				sendJSONError(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			list = append(list, c)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(list)
+
+	case "PUT":
+		var c CategoryShelfLife
+		if err := json.NewDecoder(r.Body).Decode(&c); err != nil {
+			sendJSONError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if c.Category == "" {
+			sendJSONError(w, "category required", http.StatusBadRequest)
+			return
+		}
+		if !isValidStorageType(c.StorageType) {
+			sendJSONError(w, "storage_type は fridge / freezer / room のいずれかです", http.StatusBadRequest)
+			return
+		}
+
+		var err error
+		if c.Days <= 0 {
+			_, err = db.Exec("DELETE FROM category_shelf_life WHERE category = ? AND storage_type = ?", c.Category, c.StorageType)
+		} else {
+			_, err = db.Exec(`INSERT INTO category_shelf_life(category, storage_type, days) VALUES(?, ?, ?)
+				ON CONFLICT(category, storage_type) DO UPDATE SET days = excluded.days`, c.Category, c.StorageType, c.Days)
+		}
+		if err != nil {
+			sendJSONError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{"status": "success"})
+
+	default:
+		sendJSONError(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+	}
+}
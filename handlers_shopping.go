@@ -0,0 +1,418 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"kimichan/units"
+)
+
+type ShoppingItem struct {
+	ID             int    `json:"id"`
+	CatalogID      int    `json:"catalog_id"`
+	Name           string `json:"name"`
+	Classification string `json:"classification"`
+	Category       string `json:"category"`
+	Quantity       string `json:"quantity"`
+	Unit           string `json:"unit"`
+	Note           string `json:"note"`
+	RecipeID       int    `json:"recipe_id,omitempty"`
+	RecipeName     string `json:"recipe_name,omitempty"`
+	Checked        bool   `json:"checked"`
+	CreatedBy      string `json:"created_by"`
+	CreatedAt      string `json:"created_at"`
+}
+
+func handleShopping(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		getShoppingList(w, r)
+	case "POST":
+		addShoppingItems(w, r)
+	case "PUT":
+		updateShoppingItem(w, r)
+	case "DELETE":
+		deleteShoppingItems(w, r)
+	default:
+		sendJSONError(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// チェック前 → チェック済みの順、同じ売り場（カテゴリ）をまとめて返す
+func getShoppingList(w http.ResponseWriter, _ *http.Request) {
+	rows, err := db.Query(`
+		SELECT s.id, s.catalog_id, c.name, c.classification, COALESCE(c.category, ''),
+			COALESCE(s.quantity, ''), COALESCE(s.unit, ''), COALESCE(s.note, ''),
+			COALESCE(s.recipe_id, 0), COALESCE(r.name, ''), s.checked, COALESCE(s.created_by, ''), s.created_at
+		FROM shopping_list s
+		JOIN item_catalog c ON s.catalog_id = c.id
+		LEFT JOIN recipes r ON s.recipe_id = r.id
+		ORDER BY s.checked ASC, c.category ASC, COALESCE(NULLIF(c.kana, ''), c.name) ASC, s.id ASC`)
+	if err != nil {
+		sendJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	items := []ShoppingItem{}
+	for rows.Next() {
+		var s ShoppingItem
+		if err := rows.Scan(&s.ID, &s.CatalogID, &s.Name, &s.Classification, &s.Category,
+			&s.Quantity, &s.Unit, &s.Note, &s.RecipeID, &s.RecipeName, &s.Checked, &s.CreatedBy, &s.CreatedAt); err != nil {
+			sendJSONError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		items = append(items, s)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(items)
+}
+
+// POST /api/shopping
+//
+//	{"recipe_id": 3}                                 レシピの在庫切れの材料をまとめて追加
+//	{"catalog_id": 5, "quantity": "2", "unit": "個"}  カタログから1件追加
+//
+// まだチェックしていない同じ品目が既にあれば追加しない
+func addShoppingItems(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		RecipeID  int    `json:"recipe_id"`
+		CatalogID int    `json:"catalog_id"`
+		Quantity  string `json:"quantity"`
+		Unit      string `json:"unit"`
+		Note      string `json:"note"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.RecipeID == 0 && req.CatalogID == 0 {
+		sendJSONError(w, "recipe_id or catalog_id required", http.StatusBadRequest)
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		sendJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	type candidate struct {
+		catalogID      int
+		quantity, unit string
+		note           string
+		recipeID       interface{}
+	}
+	var candidates []candidate
+
+	if req.RecipeID != 0 {
+		var recipeName string
+		err := tx.QueryRow("SELECT name FROM recipes WHERE id = ? AND deleted_at IS NULL", req.RecipeID).Scan(&recipeName)
+		if err == sql.ErrNoRows {
+			tx.Rollback()
+			sendJSONError(w, "レシピが見つかりません", http.StatusNotFound)
+			return
+		} else if err != nil {
+			tx.Rollback()
+			sendJSONError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		rows, err := tx.Query(`
+			SELECT ri.catalog_id, COALESCE(ri.amount, ''), COALESCE(ri.unit, ''), COALESCE(ri.details, '')
+			FROM recipe_ingredients ri
+			JOIN item_catalog c ON ri.catalog_id = c.id
+			WHERE ri.recipe_id = ? AND `+stockCountSQL+` = 0
+			ORDER BY ri.id ASC`, req.RecipeID)
+		if err != nil {
+			tx.Rollback()
+			sendJSONError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		for rows.Next() {
+			c := candidate{recipeID: req.RecipeID}
+			if err := rows.Scan(&c.catalogID, &c.quantity, &c.unit, &c.note); err != nil {
+				rows.Close()
+				tx.Rollback()
+				sendJSONError(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			candidates = append(candidates, c)
+		}
+		rows.Close()
+	} else {
+		var exists int
+		if err := tx.QueryRow("SELECT COUNT(*) FROM item_catalog WHERE id = ?", req.CatalogID).Scan(&exists); err != nil || exists == 0 {
+			tx.Rollback()
+			sendJSONError(w, "カタログに存在しません", http.StatusBadRequest)
+			return
+		}
+		candidates = append(candidates, candidate{
+			catalogID: req.CatalogID, quantity: req.Quantity, unit: req.Unit, note: req.Note,
+		})
+	}
+
+	actor := ""
+	if u := currentUser(r); u != nil {
+		actor = u.Username
+	}
+
+	added, skipped := 0, 0
+	for _, c := range candidates {
+		var dup int
+		tx.QueryRow("SELECT COUNT(*) FROM shopping_list WHERE catalog_id = ? AND checked = 0", c.catalogID).Scan(&dup)
+		if dup > 0 {
+			skipped++
+			continue
+		}
+		_, err := tx.Exec("INSERT INTO shopping_list(catalog_id, quantity, unit, note, recipe_id, created_by) VALUES(?, ?, ?, ?, ?, ?)",
+			c.catalogID, c.quantity, c.unit, c.note, c.recipeID, actor)
+		if err != nil {
+			tx.Rollback()
+			sendJSONError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		added++
+	}
+	if err := tx.Commit(); err != nil {
+		sendJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{"status": "success", "added": added, "skipped": skipped})
+}
+
+// PUT /api/shopping {"id": 1, "checked": true}  quantity / unit / note も変更できる（省略時はそのまま）
+func updateShoppingItem(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ID       int     `json:"id"`
+		Checked  *bool   `json:"checked"`
+		Quantity *string `json:"quantity"`
+		Unit     *string `json:"unit"`
+		Note     *string `json:"note"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.ID == 0 {
+		sendJSONError(w, "id required", http.StatusBadRequest)
+		return
+	}
+
+	var sets []string
+	var args []interface{}
+	if req.Checked != nil {
+		sets = append(sets, "checked = ?")
+		args = append(args, *req.Checked)
+	}
+	if req.Quantity != nil {
+		sets = append(sets, "quantity = ?")
+		args = append(args, *req.Quantity)
+	}
+	if req.Unit != nil {
+		sets = append(sets, "unit = ?")
+		args = append(args, *req.Unit)
+	}
+	if req.Note != nil {
+		sets = append(sets, "note = ?")
+		args = append(args, *req.Note)
+	}
+	if len(sets) == 0 {
+		sendJSONError(w, "nothing to update", http.StatusBadRequest)
+		return
+	}
+	args = append(args, req.ID)
+
+	res, err := db.Exec("UPDATE shopping_list SET "+strings.Join(sets, ", ")+" WHERE id = ?", args...)
+	if err != nil {
+		sendJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		sendJSONError(w, "not found", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "updated"})
+}
+
+// DELETE /api/shopping?id=1         1件削除
+// DELETE /api/shopping?checked=true チェック済みをまとめて削除（買わなかったものを片付ける）
+func deleteShoppingItems(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	var res sql.Result
+	var err error
+	switch {
+	case q.Get("id") != "":
+		id, convErr := strconv.Atoi(q.Get("id"))
+		if convErr != nil {
+			sendJSONError(w, "id must be a number", http.StatusBadRequest)
+			return
+		}
+		res, err = db.Exec("DELETE FROM shopping_list WHERE id = ?", id)
+	case q.Get("checked") == "true":
+		res, err = db.Exec("DELETE FROM shopping_list WHERE checked = 1")
+	default:
+		sendJSONError(w, "id or checked=true required", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		sendJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	n, _ := res.RowsAffected()
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"status": "deleted", "deleted": n})
+}
+
+// POST /api/shopping/purchase {"location": "冷蔵庫"}
+// チェック済みの品目を在庫に移してリストから消す。
+// 食材はリストの単位（空ならカタログの default_unit）で在庫に追加する。リストの単位が default_unit と
+// 違うときは換算係数で default_unit に直し、直せなければ量不明 -1 にする（数値だけ別の単位に移さない）。
+// 数量が数字でなければ量不明 -1。
+// 調味料はストックを「あり」にする。場所を省略すると「その他」
+func handleShoppingPurchase(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		sendJSONError(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req struct {
+		LocationID int    `json:"location_id"`
+		Location   string `json:"location"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			sendJSONError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		sendJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	locID, locName, err := resolveLocation(tx, req.LocationID, req.Location)
+	if err != nil {
+		tx.Rollback()
+		sendJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	type purchase struct {
+		shoppingID, catalogID    int
+		classification, quantity string
+		unit, defaultUnit        string
+	}
+	rows, err := tx.Query(`
+		SELECT s.id, s.catalog_id, c.classification, COALESCE(s.quantity, ''), COALESCE(s.unit, ''), COALESCE(c.default_unit, '')
+		FROM shopping_list s
+		JOIN item_catalog c ON s.catalog_id = c.id
+		WHERE s.checked = 1
+		ORDER BY s.id ASC`)
+	if err != nil {
+		tx.Rollback()
+		sendJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	var purchases []purchase
+	for rows.Next() {
+		var p purchase
+		if err := rows.Scan(&p.shoppingID, &p.catalogID, &p.classification, &p.quantity, &p.unit, &p.defaultUnit); err != nil {
+			rows.Close()
+			tx.Rollback()
+			sendJSONError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		purchases = append(purchases, p)
+	}
+	rows.Close()
+
+	ingredients := []Ingredient{}
+	seasonings := 0
+	for _, p := range purchases {
+		if p.classification == "調味料" {
+			var seasID int
+			err := tx.QueryRow("SELECT id FROM refrigerator_seasonings WHERE catalog_id = ?", p.catalogID).Scan(&seasID)
+			if err == nil {
+				err = setSeasoningStatus(tx, r, seasID, SeasoningStatusInStock)
+			} else if err == sql.ErrNoRows {
+				_, err = insertSeasoning(tx, r, p.catalogID, SeasoningStatusInStock)
+			}
+			if err != nil {
+				tx.Rollback()
+				sendJSONError(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			seasonings++
+		} else {
+			item, err := purchasedIngredient(tx, p.catalogID, p.quantity, p.unit, p.defaultUnit)
+			if err != nil {
+				tx.Rollback()
+				sendJSONError(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			item.LocationID, item.Location = locID, locName
+			if err := insertIngredient(tx, r, &item); err != nil {
+				tx.Rollback()
+				sendJSONError(w, fmt.Sprintf("在庫への追加に失敗しました: %v", err), http.StatusInternalServerError)
+				return
+			}
+			ingredients = append(ingredients, item)
+		}
+
+		if _, err := tx.Exec("DELETE FROM shopping_list WHERE id = ?", p.shoppingID); err != nil {
+			tx.Rollback()
+			sendJSONError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		sendJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":      "purchased",
+		"ingredients": ingredients,
+		"seasonings":  seasonings,
+	})
+}
+
+// 買った食材の在庫の行を作る。単位はリストの単位、空ならカタログの default_unit。
+// 両方あって違うときは default_unit に換算し、換算できなければ量不明（-1）にする
+func purchasedIngredient(tx *sql.Tx, catalogID int, quantity, unit, defaultUnit string) (Ingredient, error) {
+	item := Ingredient{CatalogID: catalogID, Amount: -1, Unit: defaultUnit}
+	amount, err := strconv.ParseFloat(strings.TrimSpace(quantity), 64)
+	if err != nil || amount <= 0 {
+		if unit != "" && defaultUnit == "" {
+			item.Unit = unit
+		}
+		return item, nil
+	}
+
+	switch {
+	case unit == "" || unit == defaultUnit:
+		item.Amount = amount
+	case defaultUnit == "":
+		item.Amount, item.Unit = amount, unit
+	default:
+		f, err := loadConversionFactors(tx, catalogID)
+		if err != nil {
+			return item, err
+		}
+		if res, err := units.Convert(amount, unit, defaultUnit, f); err == nil {
+			item.Amount = roundAmount(res.Value)
+		}
+	}
+	return item, nil
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"kimichan/quantity"
+	"kimichan/units"
+)
+
+// カタログ項目の換算係数を読む（未設定の項目はゼロ値）
+func loadConversionFactors(q queryRower, catalogID int) (units.Factors, error) {
+	var f units.Factors
+	var density, pieceGrams sql.NullFloat64
+	var pieceUnit sql.NullString
+	err := q.QueryRow("SELECT density, piece_unit, piece_grams FROM item_catalog WHERE id = ?", catalogID).
+		Scan(&density, &pieceUnit, &pieceGrams)
+	if err != nil {
+		return f, err
+	}
+	f.Density = density.Float64
+	f.PieceUnit = pieceUnit.String
+	f.PieceGrams = pieceGrams.Float64
+	return f, nil
+}
+
+// GET /api/catalog/convert?catalog_id=3&value=2&from=個&to=g
+// GET /api/catalog/convert?catalog_id=3&amount=大さじ1と1/2&to=g   （分量文字列で指定）
+// catalog_id を省略すると体積どうし・重さどうしの共通換算だけ行う。
+// 変換できないときも 200 で convertible: false と理由を返す
+func handleConvertUnits(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		sendJSONError(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	q := r.URL.Query()
+
+	to := q.Get("to")
+	if to == "" {
+		sendJSONError(w, "to required", http.StatusBadRequest)
+		return
+	}
+
+	var value float64
+	from := q.Get("from")
+	if amount := q.Get("amount"); amount != "" {
+		parsed, err := quantity.Parse(amount)
+		if err != nil || !parsed.HasValue() {
+			sendJSONError(w, "分量を読み取れません: "+amount, http.StatusBadRequest)
+			return
+		}
+		value, from = parsed.Value, parsed.Unit
+	} else {
+		v, err := strconv.ParseFloat(q.Get("value"), 64)
+		if err != nil {
+			sendJSONError(w, "value must be a number", http.StatusBadRequest)
+			return
+		}
+		value = v
+	}
+	if from == "" {
+		sendJSONError(w, "from required", http.StatusBadRequest)
+		return
+	}
+
+	var factors units.Factors
+	catalogID := 0
+	if s := q.Get("catalog_id"); s != "" {
+		id, err := strconv.Atoi(s)
+		if err != nil {
+			sendJSONError(w, "catalog_id must be a number", http.StatusBadRequest)
+			return
+		}
+		catalogID = id
+		factors, err = loadConversionFactors(db, catalogID)
+		if err == sql.ErrNoRows {
+			sendJSONError(w, "カタログに存在しません", http.StatusNotFound)
+			return
+		} else if err != nil {
+			sendJSONError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	res := map[string]interface{}{
+		"catalog_id": catalogID,
+		"value":      value,
+		"from":       from,
+		"to":         to,
+	}
+	converted, err := units.Convert(value, from, to, factors)
+	if err != nil {
+		res["convertible"] = false
+		res["reason"] = err.Error()
+	} else {
+		res["convertible"] = true
+		res["result"] = converted.Value
+		res["via"] = converted.Via
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(res)
+}
@@ -0,0 +1,136 @@
+// Package kana は検索用に日本語の表記ゆれを畳み込みます。
+//
+//   - 全角英数字・記号 → 半角、半角カナ → 全角（濁点・半濁点も合成）
+//   - カタカナ → ひらがな、英字は小文字
+//   - よく使う食材・調味料の漢字表記 → ひらがな（玉ねぎ・玉葱・タマネギ → たまねぎ）
+//
+// 検索する文字列とされる文字列の両方に同じ Fold をかけて比べる前提です。
+package kana
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// 漢字・混ぜ書きの表記ゆれ。キーはカタカナをひらがなにした後の形で書く
+var variants = map[string]string{
+	"玉ねぎ": "たまねぎ", "玉葱": "たまねぎ", "葱": "ねぎ", "長ねぎ": "ながねぎ", "長葱": "ながねぎ",
+	"人参": "にんじん", "大蒜": "にんにく", "生姜": "しょうが", "生薑": "しょうが",
+	"胡瓜": "きゅうり", "茄子": "なす", "南瓜": "かぼちゃ", "牛蒡": "ごぼう", "大根": "だいこん",
+	"白菜": "はくさい", "蓮根": "れんこん", "筍": "たけのこ", "韮": "にら", "三つ葉": "みつば",
+	"馬鈴薯": "じゃがいも", "じゃが芋": "じゃがいも", "薩摩芋": "さつまいも", "さつま芋": "さつまいも",
+	"里芋": "さといも", "長芋": "ながいも", "椎茸": "しいたけ", "舞茸": "まいたけ", "榎茸": "えのき",
+	"蒟蒻": "こんにゃく", "林檎": "りんご", "檸檬": "れもん", "胡麻": "ごま",
+	"玉子": "たまご", "卵": "たまご", "鶏肉": "とりにく", "鳥肉": "とりにく", "とり肉": "とりにく",
+	"海老": "えび", "烏賊": "いか", "蛸": "たこ", "鮭": "さけ", "鯖": "さば", "鰤": "ぶり",
+	"醤油": "しょうゆ", "味醂": "みりん", "味噌": "みそ", "胡椒": "こしょう",
+	"片栗粉": "かたくりこ", "小麦粉": "こむぎこ",
+}
+
+// 長いキーから順に当てる（「長葱」を「葱」より先に）
+var variantKeys = func() [][]rune {
+	keys := make([][]rune, 0, len(variants))
+	for k := range variants {
+		keys = append(keys, []rune(k))
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if len(keys[i]) != len(keys[j]) {
+			return len(keys[i]) > len(keys[j])
+		}
+		return string(keys[i]) < string(keys[j])
+	})
+	return keys
+}()
+
+// 半角カナ（U+FF66〜U+FF9D）に対応する全角カタカナ
+var halfwidthKana = []rune("ヲァィゥェォャュョッーアイウエオカキクケコサシスセソタチツテトナニヌネノハヒフヘホマミムメモヤユヨラリルレロワン")
+
+// Fold は表記ゆれを畳み込んだ文字列を返します。
+func Fold(s string) string {
+	folded, _ := FoldMap(s)
+	return folded
+}
+
+// FoldMap は Fold の結果と、結果の各文字（rune）が元の文字列の何文字目から来たかを返します。
+// 検索結果の抜粋で、畳み込んだ文字列上の一致位置を元の文字列に戻すために使います。
+func FoldMap(s string) (string, []int) {
+	out := make([]rune, 0, len(s))
+	src := make([]int, 0, len(s))
+
+	for i, r := range []rune(s) {
+		switch {
+		case r >= '！' && r <= '～':
+			r -= 0xFEE0
+		case r == '　':
+			r = ' '
+		case r >= 0xFF66 && r <= 0xFF9D:
+			r = halfwidthKana[r-0xFF66]
+		case r == 'ﾞ' || r == '゛' || r == '゙':
+			if n := len(out); n > 0 {
+				out[n-1] = voiced(out[n-1])
+			}
+			continue
+		case r == 'ﾟ' || r == '゜' || r == '゚':
+			if n := len(out); n > 0 {
+				out[n-1] = semiVoiced(out[n-1])
+			}
+			continue
+		}
+		if r >= 'ァ' && r <= 'ヶ' {
+			r -= 0x60
+		}
+		out = append(out, unicode.ToLower(r))
+		src = append(src, i)
+	}
+
+	// 漢字表記をひらがなに置き換える
+	var b strings.Builder
+	mapped := make([]int, 0, len(out))
+	for i := 0; i < len(out); {
+		key := matchVariant(out[i:])
+		if key == nil {
+			b.WriteRune(out[i])
+			mapped = append(mapped, src[i])
+			i++
+			continue
+		}
+		for _, r := range variants[string(key)] {
+			b.WriteRune(r)
+			mapped = append(mapped, src[i])
+		}
+		i += len(key)
+	}
+	return b.String(), mapped
+}
+
+func matchVariant(rs []rune) []rune {
+	for _, key := range variantKeys {
+		if len(key) > len(rs) {
+			continue
+		}
+		if string(rs[:len(key)]) == string(key) {
+			return key
+		}
+	}
+	return nil
+}
+
+// 濁点を合成する（か → が、う → ゔ）。付けられない文字はそのまま
+func voiced(r rune) rune {
+	switch {
+	case r == 'う':
+		return 'ゔ'
+	case strings.ContainsRune("かきくけこさしすせそたちつてとはひふへほ", r):
+		return r + 1
+	}
+	return r
+}
+
+// 半濁点を合成する（は → ぱ）
+func semiVoiced(r rune) rune {
+	if strings.ContainsRune("はひふへほ", r) {
+		return r + 2
+	}
+	return r
+}
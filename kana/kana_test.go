@@ -0,0 +1,57 @@
+package kana
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFold(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		// 漢字・混ぜ書きの表記ゆれ
+		{"玉ねぎ", "たまねぎ"},
+		{"玉葱", "たまねぎ"},
+		{"タマネギ", "たまねぎ"},
+		{"長葱", "ながねぎ"}, // 「葱」より長いキーが先
+		{"馬鈴薯", "じゃがいも"},
+		{"醤油", "しょうゆ"},
+
+		// 半角カナと濁点・半濁点
+		{"ﾀﾏﾈｷﾞ", "たまねぎ"},
+		{"ｼﾞｬｶﾞｲﾓ", "じゃがいも"},
+		{"ﾊﾟﾌﾟﾘｶ", "ぱぷりか"},
+		{"ｳﾞｨﾈｶﾞｰ", "ゔぃねがー"},
+		{"ヴィネガー", "ゔぃねがー"},
+		{"か゛", "が"},
+		{"は゜", "ぱ"},
+		{"ﾞあ", "あ"}, // 前に文字の無い濁点は捨てる
+
+		// 全角英数字・スペース
+		{"ＡＢＣ１２３", "abc123"},
+		{"Olive　Oil", "olive oil"},
+	}
+	for _, tt := range tests {
+		if got := Fold(tt.in); got != tt.want {
+			t.Errorf("Fold(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestFoldMap(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+		src  []int
+	}{
+		{"ﾊﾟﾌﾟﾘｶ", "ぱぷりか", []int{0, 2, 4, 5}},
+		{"新玉葱", "新たまねぎ", []int{0, 1, 1, 1, 1}},
+		{"ＡＢＣ", "abc", []int{0, 1, 2}},
+	}
+	for _, tt := range tests {
+		got, src := FoldMap(tt.in)
+		if got != tt.want || !reflect.DeepEqual(src, tt.src) {
+			t.Errorf("FoldMap(%q) = %q, %v, want %q, %v", tt.in, got, src, tt.want, tt.src)
+		}
+	}
+}
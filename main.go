@@ -10,6 +10,8 @@ import (
 	"os"
 	"path/filepath"
 
+	"kimichan/schema"
+
 	_ "github.com/mattn/go-sqlite3"
 )
 
@@ -36,7 +38,7 @@ func main() {
 	}
 
 	dbPath := filepath.Join(DataDir, "kimichan.db")
-	db, err = sql.Open("sqlite3", dbPath)
+	db, err = sql.Open("sqlite3", schema.DSN(dbPath))
 	if err != nil {
 		log.Fatalf("DB connection failed: %v", err)
 	}
@@ -45,20 +47,52 @@ func main() {
 	if err := initDatabase(); err != nil {
 		log.Fatalf("DB init failed: %v", err)
 	}
+	if err := bootstrapAdmin(); err != nil {
+		log.Fatalf("Admin bootstrap failed: %v", err)
+	}
+	if err := initRecipeSearch(); err != nil {
+		log.Fatalf("Recipe search init failed: %v", err)
+	}
+	if err := startExpiryScanner(); err != nil {
+		log.Fatalf("Expiry scanner failed: %v", err)
+	}
 
 	mux := http.NewServeMux()
 
-	// ハンドラ登録（調味料は削除済み）
+	// ハンドラ登録
+	mux.HandleFunc("/api/login", handleLogin)
+	mux.HandleFunc("/api/logout", handleLogout)
+	mux.HandleFunc("/api/me", handleMe)
 	mux.HandleFunc("/api/catalog", handleCatalog)
 	mux.HandleFunc("/api/catalog/usage", handleCatalogUsage)
 	mux.HandleFunc("/api/catalog/export", exportCatalogCSV)
+	mux.HandleFunc("/api/catalog/merges", handleCatalogMerges)
+	mux.HandleFunc("/api/catalog/aliases", handleCatalogAliases)
+	mux.HandleFunc("/api/catalog/shelf_life", handleCategoryShelfLife)
+	mux.HandleFunc("/api/catalog/convert", handleConvertUnits)
 	mux.HandleFunc("/api/ingredients", handleIngredients)
+	mux.HandleFunc("/api/ingredients/consume", handleConsumeIngredient)
+	mux.HandleFunc("/api/ingredients/history", handleIngredientHistory)
+	mux.HandleFunc("/api/ingredients/expiring", handleExpiringIngredients)
+	mux.HandleFunc("/api/seasonings", handleSeasonings)
+	mux.HandleFunc("/api/seasonings/init", handleSeasoningsInit)
 	mux.HandleFunc("/api/recipes", handleRecipes)
 	mux.HandleFunc("/api/recipes/ingredients", handleRecipeIngredients)
+	mux.HandleFunc("/api/recipes/search", handleRecipeSearch)
+	mux.HandleFunc("/api/recipes/import_url", handleRecipeImportURL)
+	mux.HandleFunc("/api/recipes/export", handleRecipeExport)
+	mux.HandleFunc("/api/recipes/suggest", handleRecipeSuggest)
+	mux.HandleFunc("/api/recipes/cook", handleRecipeCook)
+	mux.HandleFunc("/api/recipes/trash", handleRecipeTrash)
+	mux.HandleFunc("/api/recipes/restore", handleRecipeRestore)
+	mux.HandleFunc("/api/shopping", handleShopping)
+	mux.HandleFunc("/api/shopping/purchase", handleShoppingPurchase)
 	mux.HandleFunc("/api/locations", handleLocations)
 	mux.HandleFunc("/import/catalog", handleCatalogImport)
 	mux.HandleFunc("/api/upload", handleUpload)
 	mux.HandleFunc("/api/fridge_photos", handleFridgePhotos)
+	mux.HandleFunc("/api/audit", handleAudit)
+	mux.HandleFunc("/api/reports/waste", handleWasteReport)
 
 	// 静的ファイル（画像とHTML）
 	mux.Handle("/images/", http.StripPrefix("/images/", http.FileServer(http.Dir(imagesPath))))
@@ -68,26 +102,8 @@ func main() {
 
 	fmt.Println("Server is running at http://localhost:8080")
 
-	// ★Basic認証を適用して起動
-	if err := http.ListenAndServe(":8080", basicAuth(mux)); err != nil {
+	// ログイン必須で起動（users テーブルで認証）
+	if err := http.ListenAndServe(":8080", authMiddleware(mux)); err != nil {
 		log.Fatal(err)
 	}
 }
-
-// Basic認証ミドルウェア
-func basicAuth(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// ▼▼ ここでIDとパスワードを設定してください ▼▼
-		const expectedUser = "wasabi"  // ← 好きなIDに変更
-		const expectedPass = "karashi" // ← 好きなパスワードに変更
-		// ▲▲▲▲▲▲▲▲▲▲▲▲▲▲▲▲▲▲▲▲▲▲▲▲▲▲▲▲▲▲▲▲
-
-		user, pass, ok := r.BasicAuth()
-		if !ok || user != expectedUser || pass != expectedPass {
-			w.Header().Set("WWW-Authenticate", `Basic realm="Restricted"`)
-			http.Error(w, "Unauthorized", http.StatusUnauthorized)
-			return
-		}
-		next.ServeHTTP(w, r)
-	})
-}
@@ -12,6 +12,14 @@ type CatalogItem struct {
 	Classification string `json:"classification"`
 	Category       string `json:"category"`
 	DefaultUnit    string `json:"default_unit"`
+	// 日持ち日数（未設定なら null でカテゴリの目安を使う）
+	ShelfLifeFridge  *int `json:"shelf_life_fridge"`
+	ShelfLifeFreezer *int `json:"shelf_life_freezer"`
+	ShelfLifeRoom    *int `json:"shelf_life_room"`
+	// 単位換算の係数（1ml あたりの g、1つあたりの g）
+	Density    *float64 `json:"density"`
+	PieceUnit  *string  `json:"piece_unit"`
+	PieceGrams *float64 `json:"piece_grams"`
 }
 
 type Ingredient struct {
@@ -20,12 +28,15 @@ type Ingredient struct {
 	Amount         float64 `json:"amount"`
 	Unit           string  `json:"unit"`
 	ExpirationDate string  `json:"expiration_date"`
+	LocationID     int     `json:"location_id"`
 	Location       string  `json:"location"`
 	CreatedAt      string  `json:"created_at"`
 	UpdatedAt      string  `json:"updated_at"`
 	Name           string  `json:"name,omitempty"`
 	Kana           string  `json:"kana,omitempty"`
 	RecipeCount    int     `json:"recipe_count"`
+	// 賞味期限を自動で入れたときの根拠
+	ExpirationRule *ShelfLifeRule `json:"expiration_rule,omitempty"`
 }
 
 type Seasoning struct {
@@ -62,16 +73,18 @@ type RecipeIngredient struct {
 }
 
 type FridgePhoto struct {
-	ID        int    `json:"id"`
-	ImagePath string `json:"image_path"`
-	Location  string `json:"location"`
-	CreatedAt string `json:"created_at"`
+	ID         int    `json:"id"`
+	ImagePath  string `json:"image_path"`
+	LocationID int    `json:"location_id"`
+	Location   string `json:"location"`
+	CreatedAt  string `json:"created_at"`
 }
 
 type Location struct {
-	ID       int    `json:"id"`
-	Name     string `json:"name"`
-	Priority int    `json:"priority"`
+	ID          int    `json:"id"`
+	Name        string `json:"name"`
+	Priority    int    `json:"priority"`
+	StorageType string `json:"storage_type"`
 }
 
 type NullString struct {
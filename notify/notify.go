@@ -0,0 +1,142 @@
+// Package notify は賞味期限アラートなどの通知の送り先を抽象化します。
+// ログ出力・Webhook・メール (SMTP) を環境変数で切り替えられます。
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"mime"
+	"net/http"
+	"net/smtp"
+	"os"
+	"strings"
+	"time"
+)
+
+// 通知1件分。Data は Webhook では JSON としてそのまま送る
+type Message struct {
+	Subject string      `json:"subject"`
+	Body    string      `json:"body"`
+	Data    interface{} `json:"data,omitempty"`
+}
+
+type Notifier interface {
+	Notify(ctx context.Context, m Message) error
+}
+
+// 標準ログに書くだけの通知先（既定）
+type LogNotifier struct {
+	Logger *log.Logger
+}
+
+func (n LogNotifier) Notify(_ context.Context, m Message) error {
+	logger := n.Logger
+	if logger == nil {
+		logger = log.Default()
+	}
+	logger.Printf("[notify] %s\n%s", m.Subject, m.Body)
+	return nil
+}
+
+// Message を JSON で POST する通知先
+type WebhookNotifier struct {
+	URL    string
+	Client *http.Client
+}
+
+func (n WebhookNotifier) Notify(ctx context.Context, m Message) error {
+	body, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", n.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := n.Client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
+
+// メールで送る通知先。Username が空なら認証なしで送る
+type SMTPNotifier struct {
+	Addr     string // host:port
+	Username string
+	Password string
+	From     string
+	To       []string
+}
+
+func (n SMTPNotifier) Notify(_ context.Context, m Message) error {
+	var auth smtp.Auth
+	if n.Username != "" {
+		host := n.Addr
+		if i := strings.LastIndex(host, ":"); i >= 0 {
+			host = host[:i]
+		}
+		auth = smtp.PlainAuth("", n.Username, n.Password, host)
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", n.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(n.To, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.BEncoding.Encode("UTF-8", m.Subject))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	msg.WriteString("\r\n")
+	msg.WriteString(strings.ReplaceAll(m.Body, "\n", "\r\n"))
+
+	return smtp.SendMail(n.Addr, auth, n.From, n.To, msg.Bytes())
+}
+
+// 環境変数から通知先を作る
+//
+//	KIMICHAN_NOTIFY=log|webhook|smtp （省略時は log）
+//	KIMICHAN_WEBHOOK_URL
+//	KIMICHAN_SMTP_ADDR (host:port), KIMICHAN_SMTP_USER, KIMICHAN_SMTP_PASSWORD,
+//	KIMICHAN_SMTP_FROM, KIMICHAN_SMTP_TO (カンマ区切り)
+func FromEnv() (Notifier, error) {
+	switch kind := os.Getenv("KIMICHAN_NOTIFY"); kind {
+	case "", "log":
+		return LogNotifier{}, nil
+	case "webhook":
+		url := os.Getenv("KIMICHAN_WEBHOOK_URL")
+		if url == "" {
+			return nil, fmt.Errorf("KIMICHAN_WEBHOOK_URL が設定されていません")
+		}
+		return WebhookNotifier{URL: url}, nil
+	case "smtp":
+		n := SMTPNotifier{
+			Addr:     os.Getenv("KIMICHAN_SMTP_ADDR"),
+			Username: os.Getenv("KIMICHAN_SMTP_USER"),
+			Password: os.Getenv("KIMICHAN_SMTP_PASSWORD"),
+			From:     os.Getenv("KIMICHAN_SMTP_FROM"),
+		}
+		for _, to := range strings.Split(os.Getenv("KIMICHAN_SMTP_TO"), ",") {
+			if to = strings.TrimSpace(to); to != "" {
+				n.To = append(n.To, to)
+			}
+		}
+		if n.Addr == "" || n.From == "" || len(n.To) == 0 {
+			return nil, fmt.Errorf("KIMICHAN_SMTP_ADDR / KIMICHAN_SMTP_FROM / KIMICHAN_SMTP_TO を設定してください")
+		}
+		return n, nil
+	default:
+		return nil, fmt.Errorf("不明な通知先です: %s", kind)
+	}
+}
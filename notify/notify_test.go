@@ -0,0 +1,191 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWebhookNotifier(t *testing.T) {
+	var got struct {
+		method, contentType string
+		body                map[string]interface{}
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got.method = r.Method
+		got.contentType = r.Header.Get("Content-Type")
+		if err := json.NewDecoder(r.Body).Decode(&got.body); err != nil {
+			t.Errorf("decode: %v", err)
+		}
+	}))
+	defer srv.Close()
+
+	m := Message{
+		Subject: "賞味期限が近い食材があります",
+		Body:    "牛乳（2026-10-20）",
+		Data:    map[string]interface{}{"count": 1, "items": []string{"牛乳"}},
+	}
+	if err := (WebhookNotifier{URL: srv.URL}).Notify(context.Background(), m); err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+	if got.method != "POST" || got.contentType != "application/json" {
+		t.Errorf("request = %s %s, want POST application/json", got.method, got.contentType)
+	}
+	want := map[string]interface{}{
+		"subject": "賞味期限が近い食材があります",
+		"body":    "牛乳（2026-10-20）",
+		"data":    map[string]interface{}{"count": float64(1), "items": []interface{}{"牛乳"}},
+	}
+	if !reflect.DeepEqual(got.body, want) {
+		t.Errorf("payload = %v, want %v", got.body, want)
+	}
+}
+
+func TestWebhookNotifierError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "boom", http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	err := (WebhookNotifier{URL: srv.URL}).Notify(context.Background(), Message{Subject: "x"})
+	if err == nil || !strings.Contains(err.Error(), "500") {
+		t.Errorf("Notify error = %v, want a 500 error", err)
+	}
+}
+
+// 認証なし・STARTTLS なしで1通だけ受け取る SMTP サーバー
+type fakeSMTP struct {
+	addr string
+	from string
+	to   []string
+	data string
+	done chan struct{}
+}
+
+func newFakeSMTP(t *testing.T) *fakeSMTP {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	s := &fakeSMTP{addr: ln.Addr().String(), done: make(chan struct{})}
+	go func() {
+		defer close(s.done)
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		tp := textproto.NewConn(conn)
+		tp.PrintfLine("220 localhost ESMTP")
+		for {
+			line, err := tp.ReadLine()
+			if err != nil {
+				return
+			}
+			cmd := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+			switch {
+			case cmd == "EHLO" || cmd == "HELO":
+				tp.PrintfLine("250 localhost")
+			case strings.HasPrefix(strings.ToUpper(line), "MAIL FROM:"):
+				s.from = strings.Trim(line[len("MAIL FROM:"):], "<>")
+				tp.PrintfLine("250 OK")
+			case strings.HasPrefix(strings.ToUpper(line), "RCPT TO:"):
+				s.to = append(s.to, strings.Trim(line[len("RCPT TO:"):], "<>"))
+				tp.PrintfLine("250 OK")
+			case cmd == "DATA":
+				tp.PrintfLine("354 go ahead")
+				b, err := io.ReadAll(tp.DotReader())
+				if err != nil {
+					return
+				}
+				s.data = string(b)
+				tp.PrintfLine("250 OK")
+			case cmd == "QUIT":
+				tp.PrintfLine("221 bye")
+				return
+			default:
+				tp.PrintfLine("502 not implemented")
+			}
+		}
+	}()
+	return s
+}
+
+func TestSMTPNotifier(t *testing.T) {
+	s := newFakeSMTP(t)
+	n := SMTPNotifier{Addr: s.addr, From: "kimichan@example.com", To: []string{"a@example.com", "b@example.com"}}
+	m := Message{Subject: "賞味期限アラート", Body: "牛乳\n卵"}
+	if err := n.Notify(context.Background(), m); err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+	<-s.done
+
+	if s.from != n.From || !reflect.DeepEqual(s.to, n.To) {
+		t.Errorf("envelope = %s → %v, want %s → %v", s.from, s.to, n.From, n.To)
+	}
+	msg, err := textproto.NewReader(bufio.NewReader(strings.NewReader(s.data))).ReadMIMEHeader()
+	if err != nil {
+		t.Fatalf("read header: %v", err)
+	}
+	if got := msg.Get("To"); got != "a@example.com, b@example.com" {
+		t.Errorf("To = %q", got)
+	}
+	if subject, err := new(mime.WordDecoder).DecodeHeader(msg.Get("Subject")); err != nil || subject != m.Subject {
+		t.Errorf("Subject = %q (%v), want %q", msg.Get("Subject"), err, m.Subject)
+	}
+	if got := msg.Get("Content-Type"); got != "text/plain; charset=UTF-8" {
+		t.Errorf("Content-Type = %q", got)
+	}
+	// DotReader が CRLF を LF に戻すので、本文は LF で比べる
+	if !strings.HasSuffix(s.data, "\n\n牛乳\n卵\n") {
+		t.Errorf("message = %q, want body 牛乳 / 卵", s.data)
+	}
+}
+
+func TestFromEnv(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		want    Notifier
+		wantErr bool
+	}{
+		{"既定はログ", map[string]string{}, LogNotifier{}, false},
+		{"webhook", map[string]string{"KIMICHAN_NOTIFY": "webhook", "KIMICHAN_WEBHOOK_URL": "https://example.com/hook"},
+			WebhookNotifier{URL: "https://example.com/hook"}, false},
+		{"webhook の URL なし", map[string]string{"KIMICHAN_NOTIFY": "webhook"}, nil, true},
+		{"smtp", map[string]string{"KIMICHAN_NOTIFY": "smtp", "KIMICHAN_SMTP_ADDR": "mail:587",
+			"KIMICHAN_SMTP_FROM": "k@example.com", "KIMICHAN_SMTP_TO": " a@example.com, ,b@example.com"},
+			SMTPNotifier{Addr: "mail:587", From: "k@example.com", To: []string{"a@example.com", "b@example.com"}}, false},
+		{"smtp の宛先なし", map[string]string{"KIMICHAN_NOTIFY": "smtp", "KIMICHAN_SMTP_ADDR": "mail:587",
+			"KIMICHAN_SMTP_FROM": "k@example.com"}, nil, true},
+		{"不明な通知先", map[string]string{"KIMICHAN_NOTIFY": "slack"}, nil, true},
+	}
+	keys := []string{"KIMICHAN_NOTIFY", "KIMICHAN_WEBHOOK_URL", "KIMICHAN_SMTP_ADDR", "KIMICHAN_SMTP_USER",
+		"KIMICHAN_SMTP_PASSWORD", "KIMICHAN_SMTP_FROM", "KIMICHAN_SMTP_TO"}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, k := range keys {
+				t.Setenv(k, tt.env[k])
+			}
+			got, err := FromEnv()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("FromEnv error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FromEnv = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package quantity
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// 料理で使う端数（1/4, 1/3, 1/2, 2/3, 3/4）。表示もこの形にする
+var kitchenFractions = []struct {
+	value float64
+	text  string
+}{
+	{0, ""},
+	{1.0 / 4, "1/4"},
+	{1.0 / 3, "1/3"},
+	{1.0 / 2, "1/2"},
+	{2.0 / 3, "2/3"},
+	{3.0 / 4, "3/4"},
+	{1, ""},
+}
+
+// 数字の前に書く単位（大さじ1、カップ1/2）
+var prefixDisplayUnits = map[string]bool{"大さじ": true, "小さじ": true, "カップ": true}
+
+// 数字の後ろに付く修飾語（小さじ1/2強）。それ以外は Text では表示しない
+var suffixQualifiers = map[string]bool{"強": true, "弱": true, "程度": true, "くらい": true, "ぐらい": true, "ほど": true}
+
+// 数字の前に付く大きさの修飾語（小1個）
+var sizeQualifiers = map[string]bool{"大": true, "小": true}
+
+// ParseServings は "2人分" "4〜5人前" のような表記から人数を読む（範囲なら下限）
+func ParseServings(s string) (float64, bool) {
+	q, err := Parse(s)
+	if err != nil || !q.HasValue() || !strings.HasPrefix(q.Unit, "人") {
+		return 0, false
+	}
+	return q.Value, true
+}
+
+// Scale は数値を factor 倍して台所で量りやすい値に丸める。
+// "少々" "適量" のように数値の無い分量はそのまま返す
+func (q Quantity) Scale(factor float64) Quantity {
+	if !q.HasValue() || factor == 1 {
+		return q
+	}
+	q.Value = RoundKitchen(q.Value * factor)
+	if q.Max > 0 {
+		q.Max = RoundKitchen(q.Max * factor)
+		if q.Max <= q.Value {
+			q.Max = 0
+		}
+	}
+	q.Raw = q.Text()
+	return q
+}
+
+// RoundKitchen は 10 未満を 1/4・1/3 刻みに、10 以上を整数に、100 以上を 5 刻みに丸める。
+// 0 より大きい値が 0 にならないよう、最小は 1/4
+func RoundKitchen(v float64) float64 {
+	switch {
+	case v <= 0:
+		return 0
+	case v >= 100:
+		return math.Round(v/5) * 5
+	case v >= 10:
+		return math.Round(v)
+	}
+	whole := math.Floor(v)
+	frac := v - whole
+	best := kitchenFractions[0].value
+	for _, f := range kitchenFractions {
+		if math.Abs(frac-f.value) < math.Abs(frac-best) {
+			best = f.value
+		}
+	}
+	if whole+best == 0 {
+		return kitchenFractions[1].value
+	}
+	return whole + best
+}
+
+// FormatNumber は 1.5 → "1と1/2"、0.25 → "1/4"、2 → "2" のように書く
+func FormatNumber(v float64) string {
+	whole := math.Floor(v)
+	frac := v - whole
+	for _, f := range kitchenFractions {
+		if f.text == "" || math.Abs(frac-f.value) > 1e-6 {
+			continue
+		}
+		if whole == 0 {
+			return f.text
+		}
+		return strconv.FormatFloat(whole, 'f', -1, 64) + "と" + f.text
+	}
+	return strconv.FormatFloat(math.Round(v*100)/100, 'f', -1, 64)
+}
+
+// Text は表示用の分量文字列を組み立てる（"大さじ1と1/2"、"2〜3個"、"約200g"）。
+// 数値が無ければ元の文字列を返す
+func (q Quantity) Text() string {
+	if !q.HasValue() {
+		if q.Raw != "" {
+			return q.Raw
+		}
+		return q.Qualifier
+	}
+	num := FormatNumber(q.Value)
+	if q.Max > 0 {
+		num += "〜" + FormatNumber(q.Max)
+	}
+
+	var text string
+	if prefixDisplayUnits[q.Unit] {
+		text = q.Unit + num
+	} else {
+		text = num + q.Unit
+	}
+	prefix := ""
+	for _, w := range strings.Fields(q.Qualifier) {
+		switch {
+		case w == "約":
+			prefix = "約" + prefix
+		case sizeQualifiers[w]:
+			prefix += w
+		case suffixQualifiers[w]:
+			text += w
+		}
+	}
+	return prefix + text
+}
@@ -0,0 +1,428 @@
+// Package quantity はレシピの分量文字列（"1/2本"、"大さじ1と1/2"、"2〜3個"、"少々" など）を
+// 数値・単位・修飾語に分解します。元の文字列は Raw にそのまま残します。
+package quantity
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// 数値として読めなかったときのエラー（Raw と Qualifier は埋まっている）
+var ErrNoValue = errors.New("quantity: 数値を読み取れません")
+
+// 数値はあるが分量として正しくないときのエラー（分母が 0、単位の中に数字が残る など）。Raw だけが埋まっている
+var ErrInvalid = errors.New("quantity: 分量として読めません")
+
+type Quantity struct {
+	Raw       string  `json:"raw"`
+	Value     float64 `json:"value"`               // 数値（範囲なら下限）
+	Max       float64 `json:"max,omitempty"`       // 範囲の上限（"2〜3個" の 3）。範囲でなければ 0
+	Unit      string  `json:"unit"`                // 正規化した単位（大さじ, 小さじ, カップ, 合, g, ml, 個 …）
+	Qualifier string  `json:"qualifier,omitempty"` // 少々・適量・約・強・弱 など
+}
+
+// 数値を持っているか（"少々" や "適量" は false）
+func (q Quantity) HasValue() bool {
+	return q.Value > 0
+}
+
+// DB 保存用。数値が無ければ nil（NULL）を返す
+func (q Quantity) ValueOrNil() interface{} {
+	if q.Value == 0 {
+		return nil
+	}
+	return q.Value
+}
+
+func (q Quantity) MaxOrNil() interface{} {
+	if q.Max == 0 {
+		return nil
+	}
+	return q.Max
+}
+
+// 数値の代わりに書かれる言葉
+var qualifierWords = []string{
+	"お好みで", "好みで", "お好み", "適量", "適宜", "少々", "少量", "少し", "ひとつまみ", "ひとつかみ", "ひとかけ", "たっぷり", "ひとにぎり",
+}
+
+// 数字の前に書かれる単位（長いものから順に）
+var prefixUnits = []struct{ text, unit string }{
+	{"大さじ", "大さじ"},
+	{"小さじ", "小さじ"},
+	{"大匙", "大さじ"},
+	{"小匙", "小さじ"},
+	{"カップ", "カップ"},
+	{"大", "大さじ"},
+	{"小", "小さじ"},
+}
+
+// 数字の後ろの単位の表記ゆれ
+var unitAliases = map[string]string{
+	"cc":   "ml",
+	"mL":   "ml",
+	"ML":   "ml",
+	"CC":   "ml",
+	"グラム":  "g",
+	"gr":   "g",
+	"キロ":   "kg",
+	"l":    "L",
+	"リットル": "L",
+	"cup":  "カップ",
+	"コ":    "個",
+	"ケ":    "個",
+	"ヶ":    "個",
+	"カケ":   "かけ",
+	"片":    "かけ",
+	"つ":    "個", // 一つ・2つ
+}
+
+// Parse は分量文字列を分解する。数値が読めないときは ErrNoValue を返すが、
+// "少々" のような修飾語だけの文字列は Qualifier を埋めて nil を返す。
+// 分母が 0 のもの（3/0個）や単位に数字が残るもの（200g×2）は ErrInvalid を返す。
+// 「大1」「小2杯」はさじだが、「小1個」「大1本」の 大・小 は大きさとして Qualifier に入れる。
+func Parse(s string) (Quantity, error) {
+	q := Quantity{Raw: s}
+	s = normalize(s)
+	if s == "" {
+		return q, ErrNoValue
+	}
+
+	for _, w := range qualifierWords {
+		if strings.Contains(s, w) && !containsDigit(s) {
+			q.Qualifier = w
+			return q, nil
+		}
+	}
+
+	// 前後の修飾語（約200g、小さじ1/2強）
+	if rest, ok := strings.CutPrefix(s, "約"); ok {
+		q.Qualifier = "約"
+		s = rest
+	}
+	for _, suffix := range []string{"強", "弱", "程度", "くらい", "ぐらい", "ほど"} {
+		if rest, ok := strings.CutSuffix(s, suffix); ok && rest != "" {
+			q.Qualifier = joinQualifier(q.Qualifier, suffix)
+			s = rest
+			break
+		}
+	}
+
+	// 数字の前の単位（大さじ2、カップ1/2）
+	size := "" // 「大」「小」だけの省略形。後ろに単位があれば大きさ（小1個）とみなす
+	for _, p := range prefixUnits {
+		if rest, ok := strings.CutPrefix(s, p.text); ok {
+			// 「大」「小」だけの省略形は直後が数字のときに限る
+			if len([]rune(p.text)) == 1 {
+				if !startsWithNumber(rest) {
+					continue
+				}
+				size = p.text
+			}
+			q.Unit = p.unit
+			s = rest
+			break
+		}
+	}
+
+	v, n, err := parseNumber(s)
+	if err != nil {
+		return Quantity{Raw: q.Raw}, err
+	}
+	if n == 0 {
+		// "1個" のような数字始まり以外（"半分" 以外の単位だけの文字列など）
+		for _, w := range qualifierWords {
+			if strings.Contains(s, w) {
+				q.Qualifier = joinQualifier(q.Qualifier, w)
+				return q, nil
+			}
+		}
+		return q, ErrNoValue
+	}
+	q.Value = v
+	s = s[n:]
+
+	// 範囲（2〜3個、2個〜3個）
+	unitBefore, rest := splitUnit(s)
+	if r, ok := cutRangeSep(rest); ok {
+		max, m, err := parseNumber(r)
+		if err != nil {
+			return Quantity{Raw: q.Raw}, err
+		}
+		if m > 0 {
+			q.Max = max
+			s = r[m:]
+			if unitBefore != "" {
+				s = unitBefore + strings.TrimPrefix(s, unitBefore)
+			}
+		}
+	}
+
+	// 残りは単位。「1個半」の 半 と「1/2個分」の 分 を処理する
+	unit := strings.TrimSpace(s)
+	// 「2人分」の 分 は人数の単位の一部なので残す
+	if u, ok := strings.CutSuffix(unit, "分"); ok && u != "" && u != "人" {
+		unit = u
+	}
+	if u, ok := strings.CutSuffix(unit, "半"); ok {
+		unit = u
+		if q.Max > 0 {
+			q.Max += 0.5
+		} else {
+			q.Value += 0.5
+		}
+	}
+	if containsDigit(unit) {
+		// 「200g×2」「1,2個」のように数字が残るものは単位にしない
+		return Quantity{Raw: q.Raw}, ErrInvalid
+	}
+	if size != "" && unit != "" && unit != "杯" {
+		// 「小1個」「大1本」の 大・小 はさじではなく大きさ
+		q.Unit = ""
+		q.Qualifier = joinQualifier(q.Qualifier, size)
+	}
+	if unit != "" {
+		if q.Unit != "" {
+			// 大さじ1杯 のような重ね書き
+			if unit != "杯" {
+				q.Qualifier = joinQualifier(q.Qualifier, unit)
+			}
+		} else {
+			q.Unit = normalizeUnit(unit)
+		}
+	}
+	return q, nil
+}
+
+func joinQualifier(a, b string) string {
+	if a == "" {
+		return b
+	}
+	return a + " " + b
+}
+
+func normalizeUnit(u string) string {
+	if alias, ok := unitAliases[u]; ok {
+		return alias
+	}
+	if alias, ok := unitAliases[strings.ToLower(u)]; ok {
+		return alias
+	}
+	return u
+}
+
+// 全角英数字・記号を半角にし、括弧書きを取り除く
+func normalize(s string) string {
+	var b strings.Builder
+	depth := 0
+	for _, r := range s {
+		switch {
+		case r >= '０' && r <= '９':
+			r = r - '０' + '0'
+		case r >= 'Ａ' && r <= 'Ｚ':
+			r = r - 'Ａ' + 'A'
+		case r >= 'ａ' && r <= 'ｚ':
+			r = r - 'ａ' + 'a'
+		case r == '／' || r == '⁄':
+			r = '/'
+		case r == '．':
+			r = '.'
+		case r == '，':
+			r = ','
+		case r == '～' || r == '~' || r == '〜' || r == '－' || r == '−' || r == '-':
+			r = '〜'
+		case r == '（' || r == '(':
+			depth++
+			continue
+		case r == '）' || r == ')':
+			if depth > 0 {
+				depth--
+			}
+			continue
+		case r == '　':
+			r = ' '
+		}
+		if depth > 0 {
+			continue
+		}
+		// 分数の文字（½ など）
+		if f, ok := vulgarFractions[r]; ok {
+			b.WriteString(f)
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return strings.TrimSpace(b.String())
+}
+
+var vulgarFractions = map[rune]string{
+	'½': "1/2", '⅓': "1/3", '⅔': "2/3", '¼': "1/4", '¾': "3/4",
+}
+
+func containsDigit(s string) bool {
+	for _, r := range s {
+		if unicode.IsDigit(r) || r == '半' || kanjiDigits[r] > 0 || kanjiPlaces[r] > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func startsWithNumber(s string) bool {
+	_, n, err := parseNumber(s)
+	return n > 0 || err != nil
+}
+
+var kanjiDigits = map[rune]float64{
+	'〇': 0, '一': 1, '二': 2, '三': 3, '四': 4, '五': 5, '六': 6, '七': 7, '八': 8, '九': 9,
+}
+
+// 漢数字の位（十五、二百、千二百）
+var kanjiPlaces = map[rune]float64{'十': 10, '百': 100, '千': 1000}
+
+// 先頭の数値を読み、値と読んだバイト数を返す。分母が 0 の分数は ErrInvalid。
+// 対応: 整数・桁区切り（1,000）・小数・分数（1/2）・帯分数（1と1/2、1・1/2、1 1/2）・半・漢数字（十五、二百）
+func parseNumber(s string) (float64, int, error) {
+	s0 := s
+	if rest, ok := strings.CutPrefix(s, "半"); ok {
+		// 「半分」も 1/2
+		rest = strings.TrimPrefix(rest, "分")
+		return 0.5, len(s0) - len(rest), nil
+	}
+	if v, n := readKanjiNumber(s); n > 0 {
+		return v, n, nil
+	}
+
+	whole, n := readDecimal(s)
+	if n == 0 {
+		return 0, 0, nil
+	}
+	s = s[n:]
+
+	// 分数
+	if rest, ok := strings.CutPrefix(s, "/"); ok {
+		den, m := readDecimal(rest)
+		if m == 0 {
+			return whole, n, nil
+		}
+		if den == 0 {
+			return 0, 0, ErrInvalid
+		}
+		return whole / den, len(s0) - len(rest[m:]), nil
+	}
+
+	// 帯分数（1と1/2）
+	for _, sep := range []string{"と", "・", " "} {
+		rest, ok := strings.CutPrefix(s, sep)
+		if !ok {
+			continue
+		}
+		num, m := readDecimal(rest)
+		if m == 0 {
+			continue
+		}
+		r2, ok := strings.CutPrefix(rest[m:], "/")
+		if !ok {
+			continue
+		}
+		den, k := readDecimal(r2)
+		if k == 0 {
+			continue
+		}
+		if den == 0 {
+			return 0, 0, ErrInvalid
+		}
+		return whole + num/den, len(s0) - len(r2[k:]), nil
+	}
+	return whole, n, nil
+}
+
+// 漢数字を読む（「十五」→ 15、「二百」→ 200、「一」→ 1）。漢数字で始まらなければ 0, 0
+func readKanjiNumber(s string) (float64, int) {
+	total, digit := 0.0, -1.0
+	n := 0
+	for _, r := range s {
+		if d, ok := kanjiDigits[r]; ok {
+			if digit >= 0 {
+				// 「二〇」のような位取りの書き方
+				digit = digit*10 + d
+			} else {
+				digit = d
+			}
+		} else if p, ok := kanjiPlaces[r]; ok {
+			if digit < 0 {
+				digit = 1
+			}
+			total += digit * p
+			digit = -1
+		} else {
+			break
+		}
+		n += utf8.RuneLen(r)
+	}
+	if n == 0 {
+		return 0, 0
+	}
+	if digit > 0 {
+		total += digit
+	}
+	if total == 0 {
+		return 0, 0
+	}
+	return total, n
+}
+
+// 先頭の10進数を読む。3桁ごとのカンマ（1,000）は数字の一部として扱う
+func readDecimal(s string) (float64, int) {
+	i := 0
+	dot := false
+	for i < len(s) {
+		c := s[i]
+		if c >= '0' && c <= '9' {
+			i++
+		} else if c == '.' && !dot && i > 0 && i+1 < len(s) && isDigit(s[i+1]) {
+			dot = true
+			i++
+		} else if c == ',' && !dot && i > 0 && isThousandsGroup(s[i+1:]) {
+			i++
+		} else {
+			break
+		}
+	}
+	if i == 0 {
+		return 0, 0
+	}
+	v, err := strconv.ParseFloat(strings.ReplaceAll(s[:i], ",", ""), 64)
+	if err != nil {
+		return 0, 0
+	}
+	return v, i
+}
+
+// カンマの後ろがちょうど3桁の数字か（「1,000g」「12,500」）
+func isThousandsGroup(s string) bool {
+	if len(s) < 3 || !isDigit(s[0]) || !isDigit(s[1]) || !isDigit(s[2]) {
+		return false
+	}
+	return len(s) == 3 || !isDigit(s[3])
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// "個〜3個" を ("個", "〜3個") に分ける（範囲記号が無ければ単位は空）
+func splitUnit(s string) (string, string) {
+	if i := strings.Index(s, "〜"); i > 0 {
+		return strings.TrimSpace(s[:i]), s[i:]
+	}
+	return "", s
+}
+
+func cutRangeSep(s string) (string, bool) {
+	s = strings.TrimSpace(s)
+	return strings.CutPrefix(s, "〜")
+}
@@ -0,0 +1,114 @@
+package quantity
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in        string
+		value     float64
+		max       float64
+		unit      string
+		qualifier string
+		err       error
+	}{
+		{in: "1/2本", value: 0.5, unit: "本"},
+		{in: "大さじ1と1/2", value: 1.5, unit: "大さじ"},
+		{in: "大1", value: 1, unit: "大さじ"},
+		{in: "小2杯", value: 2, unit: "小さじ"},
+		{in: "小1個", value: 1, unit: "個", qualifier: "小"},
+		{in: "大1本", value: 1, unit: "本", qualifier: "大"},
+		{in: "約大2枚", value: 2, unit: "枚", qualifier: "約 大"},
+		{in: "½カップ", value: 0.5, unit: "カップ"},
+		{in: "１００ｇ", value: 100, unit: "g"},
+		{in: "1.5kg", value: 1.5, unit: "kg"},
+		{in: "200cc", value: 200, unit: "ml"},
+		{in: "1片", value: 1, unit: "かけ"},
+		{in: "半分", value: 0.5},
+		{in: "2〜3個", value: 2, max: 3, unit: "個"},
+		{in: "2個〜3個", value: 2, max: 3, unit: "個"},
+		{in: "約200g", value: 200, unit: "g", qualifier: "約"},
+		{in: "小さじ1/2強", value: 0.5, unit: "小さじ", qualifier: "強"},
+		{in: "少々", qualifier: "少々"},
+		{in: "お好みで", qualifier: "お好みで"},
+
+		// 漢数字
+		{in: "十五g", value: 15, unit: "g"},
+		{in: "百二十g", value: 120, unit: "g"},
+		{in: "一つ", value: 1, unit: "個"},
+		{in: "2つ", value: 2, unit: "個"},
+		{in: "二人分", value: 2, unit: "人分"},
+
+		// 3桁区切り
+		{in: "1,000g", value: 1000, unit: "g"},
+		{in: "1，000g", value: 1000, unit: "g"},
+		{in: "1,00g", err: ErrInvalid},
+
+		// 読めないもの
+		{in: "", err: ErrNoValue},
+		{in: "g", err: ErrNoValue},
+		{in: "3/0個", err: ErrInvalid},
+		{in: "200g×2", err: ErrInvalid},
+	}
+	for _, tt := range tests {
+		q, err := Parse(tt.in)
+		if !errors.Is(err, tt.err) {
+			t.Errorf("Parse(%q) error = %v, want %v", tt.in, err, tt.err)
+			continue
+		}
+		if q.Raw != tt.in {
+			t.Errorf("Parse(%q).Raw = %q", tt.in, q.Raw)
+		}
+		if q.Value != tt.value || q.Max != tt.max || q.Unit != tt.unit || q.Qualifier != tt.qualifier {
+			t.Errorf("Parse(%q) = {%v %v %q %q}, want {%v %v %q %q}", tt.in,
+				q.Value, q.Max, q.Unit, q.Qualifier, tt.value, tt.max, tt.unit, tt.qualifier)
+		}
+	}
+}
+
+func TestParseServings(t *testing.T) {
+	tests := []struct {
+		in   string
+		want float64
+		ok   bool
+	}{
+		{"2人分", 2, true},
+		{"二人分", 2, true},
+		{"4人前", 4, true},
+		{"2〜3人分", 2, true},
+		{"4", 0, false},
+		{"適量", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := ParseServings(tt.in)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("ParseServings(%q) = %v, %v, want %v, %v", tt.in, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestScaleText(t *testing.T) {
+	tests := []struct {
+		in     string
+		factor float64
+		want   string
+	}{
+		{"大さじ1と1/2", 1, "大さじ1と1/2"},
+		{"大さじ1と1/2", 2, "大さじ3"},
+		{"2〜3個", 0.5, "1〜1と1/2個"},
+		{"少々", 2, "少々"},
+		{"小1個", 2, "小2個"},
+		{"約大2枚", 0.5, "約大1枚"},
+	}
+	for _, tt := range tests {
+		q, err := Parse(tt.in)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", tt.in, err)
+		}
+		if got := q.Scale(tt.factor).Text(); got != tt.want {
+			t.Errorf("Parse(%q).Scale(%v).Text() = %q, want %q", tt.in, tt.factor, got, tt.want)
+		}
+	}
+}
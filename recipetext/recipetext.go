@@ -0,0 +1,232 @@
+// Package recipetext はレシピの材料テキスト（1行に1材料）を解析します。
+//
+// 1行は「材料名,分量,詳細」の3列で、区切りには次のどれでも使えます（上にあるものほど優先）。
+//
+//   - タブ（Webサイトの表からの貼り付け）
+//   - 三点リーダ「…」「...」、コロン「：」「:」（材料名と分量。「鶏肉：200g,皮なし」のように後ろのカンマで詳細も書ける。
+//     「薄力粉…1,000g」の3桁区切りでは分けない）
+//   - カンマ「,」「，」（"..." で囲めば中にカンマを書ける。4列目以降は同じカンマでつないで詳細に含める）
+//   - 読点「、」・スペース（後ろが分量として読める場合だけ区切りとみなす）
+//
+// 「=A=」「【A】」「◎A」のような行はグループ見出しで、続く材料の group になります（「==」でグループなしに戻る）。
+// 読めない行は行番号付きのエラーとして別に返し、残りの行はそのまま使えるようにします。
+package recipetext
+
+import (
+	"encoding/csv"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"kimichan/quantity"
+)
+
+// Line は材料1行分です。
+type Line struct {
+	LineNo  int    `json:"line"` // 1始まり
+	Group   string `json:"group"`
+	Name    string `json:"name"`
+	Amount  string `json:"amount"`
+	Details string `json:"details"`
+}
+
+// LineError は読めなかった行です。
+type LineError struct {
+	LineNo  int    `json:"line"`
+	Text    string `json:"text"`
+	Message string `json:"message"`
+}
+
+func (e LineError) Error() string {
+	return fmt.Sprintf("%d行目: %s（%s）", e.LineNo, e.Message, e.Text)
+}
+
+// 行頭に付いていたら外す箇条書きの記号
+const bulletMarks = "・･-*＊•○"
+
+// グループ見出しに使われる記号（「◎A」「■ソース」）
+const groupMarks = "◎■□◆◇"
+
+// Parse は材料テキストを行ごとに解析します。空行は読み飛ばします。
+func Parse(text string) ([]Line, []LineError) {
+	var lines []Line
+	var errs []LineError
+	group := ""
+
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	for i, raw := range strings.Split(text, "\n") {
+		lineNo := i + 1
+		s := strings.TrimSpace(strings.TrimPrefix(raw, "\ufeff"))
+		if s == "" {
+			continue
+		}
+		if g, ok := groupHeader(s); ok {
+			group = g
+			continue
+		}
+
+		fields, err := splitFields(s)
+		if err != nil {
+			errs = append(errs, LineError{LineNo: lineNo, Text: s, Message: err.Error()})
+			continue
+		}
+		l := Line{LineNo: lineNo, Group: group}
+		l.Name = strings.TrimLeft(strings.TrimSpace(fields[0]), bulletMarks+groupMarks+" 　")
+		if len(fields) > 1 {
+			l.Amount = strings.TrimSpace(fields[1])
+		}
+		if len(fields) > 2 {
+			l.Details = strings.TrimSpace(fields[2])
+		}
+		if l.Name == "" {
+			errs = append(errs, LineError{LineNo: lineNo, Text: s, Message: "材料名がありません"})
+			continue
+		}
+		lines = append(lines, l)
+	}
+	return lines, errs
+}
+
+// Format は Parse の結果を「材料名,分量,詳細」のカンマ区切りのテキストに戻します。
+// グループが変わるところには「=A=」の見出しを入れ、グループが無くなるところは「==」にします。
+func Format(lines []Line) string {
+	var b strings.Builder
+	w := csv.NewWriter(&b)
+	group := ""
+	for _, l := range lines {
+		if l.Group != group {
+			w.Flush()
+			fmt.Fprintf(&b, "=%s=\n", l.Group)
+			group = l.Group
+		}
+		record := []string{l.Name, l.Amount, l.Details}
+		for len(record) > 1 && record[len(record)-1] == "" {
+			record = record[:len(record)-1]
+		}
+		w.Write(record)
+	}
+	w.Flush()
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// 「=A=」「＝A＝」「【A】」「◎A」ならグループ名を返す
+func groupHeader(s string) (string, bool) {
+	switch {
+	case strings.HasPrefix(s, "=") || strings.HasPrefix(s, "＝"):
+		return strings.Trim(s, "=＝ \t　"), true
+	case strings.HasPrefix(s, "【") && strings.HasSuffix(s, "】"):
+		return strings.TrimSpace(strings.TrimSuffix(strings.TrimPrefix(s, "【"), "】")), true
+	}
+	r := []rune(s)
+	if strings.ContainsRune(groupMarks, r[0]) {
+		// 「◎醤油…大さじ1」のように分量まで書いてあれば材料の行
+		rest := strings.TrimSpace(string(r[1:]))
+		if fields, err := splitFields(rest); err == nil && len(fields) == 1 {
+			return rest, true
+		}
+	}
+	return "", false
+}
+
+// 1行を列に分ける（最大3列）。区切りが見つからなければ材料名だけの1列
+func splitFields(s string) ([]string, error) {
+	if strings.Contains(s, "\t") {
+		return joinExtra(nonEmpty(strings.Split(s, "\t")), ","), nil
+	}
+	// 分量に「1,000g」のようなカンマが入ることがあるので、カンマより先に見る。
+	// 前にカンマや「"」があれば「鶏肉,200g,皮なし: 1cm角」のようなカンマ区切りの中身なので使わない
+	for _, sep := range []string{"…", "...", "：", ":"} {
+		if name, amount, ok := strings.Cut(s, sep); ok && !strings.ContainsAny(name, ",，\"") {
+			// 「鶏肉：200g,皮なし」のように後ろにカンマで詳細が続くこともある
+			return append([]string{name}, cutDetails(strings.TrimLeft(amount, ".…"))...), nil
+		}
+	}
+	switch {
+	case strings.Contains(s, ","):
+		return readCSV(s, ',')
+	case strings.Contains(s, "，"):
+		return readCSV(s, '，')
+	}
+	// 「塩、こしょう」「ピュアセレクト マヨネーズ」のような名前もあるので、後ろが分量として読めるときだけ区切る
+	for _, sep := range []string{"、", "　", " "} {
+		if i := strings.LastIndex(s, sep); i > 0 {
+			if amount := strings.TrimSpace(s[i+len(sep):]); amount != "" {
+				if _, err := quantity.Parse(amount); err == nil {
+					return []string{s[:i], amount}, nil
+				}
+			}
+		}
+	}
+	return []string{s}, nil
+}
+
+func readCSV(s string, comma rune) ([]string, error) {
+	r := csv.NewReader(strings.NewReader(s))
+	r.Comma = comma
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+	fields, err := r.Read()
+	if err != nil {
+		if pe, ok := err.(*csv.ParseError); ok {
+			err = pe.Err
+		}
+		if err == csv.ErrQuote || err == csv.ErrBareQuote {
+			return nil, fmt.Errorf("「\"」の閉じ忘れがあります")
+		}
+		return nil, err
+	}
+	return joinExtra(fields, string(comma)), nil
+}
+
+// 分量と詳細を最初のカンマで分ける。「1,000g」の3桁区切りのカンマでは分けず、詳細の中のカンマはそのまま残す
+func cutDetails(s string) []string {
+	for i, r := range s {
+		if r != ',' && r != '，' {
+			continue
+		}
+		after := s[i+utf8.RuneLen(r):]
+		if !isThousandsSep(s[:i], after) {
+			return []string{s[:i], after}
+		}
+	}
+	return []string{s}
+}
+
+// before の末尾が数字で、after が数字ちょうど3桁で始まるなら3桁区切り
+func isThousandsSep(before, after string) bool {
+	last, _ := utf8.DecodeLastRuneInString(before)
+	if !isDigit(last) {
+		return false
+	}
+	rs := []rune(after)
+	if len(rs) < 3 || !isDigit(rs[0]) || !isDigit(rs[1]) || !isDigit(rs[2]) {
+		return false
+	}
+	return len(rs) == 3 || !isDigit(rs[3])
+}
+
+func isDigit(r rune) bool {
+	return (r >= '0' && r <= '9') || (r >= '０' && r <= '９')
+}
+
+// 4列目以降を3列目（詳細）に sep でつなぐ
+func joinExtra(fields []string, sep string) []string {
+	if len(fields) <= 3 {
+		return fields
+	}
+	return append(fields[:2], strings.Join(fields[2:], sep))
+}
+
+// タブ区切りでは空の列（連続したタブ）を詰める
+func nonEmpty(fields []string) []string {
+	var out []string
+	for _, f := range fields {
+		if f = strings.TrimSpace(f); f != "" {
+			out = append(out, f)
+		}
+	}
+	if len(out) == 0 {
+		return []string{""}
+	}
+	return out
+}
@@ -0,0 +1,113 @@
+package recipetext
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	text := "\ufeff玉ねぎ,1個,みじん切り\r\n" +
+		"\n" +
+		"=A=\n" +
+		"醤油…大さじ2\n" +
+		"・みりん：大さじ1\n" +
+		"薄力粉...1,000g\n" +
+		"鶏もも肉：200g,皮なし，一口大\n" +
+		"水…1,200ml，常温\n" +
+		"【ソース】\n" +
+		"牛乳\t\t200ml\t常温\tよく振る\n" +
+		"鶏肉,200g,皮なし: 1cm角\n" +
+		"ねぎ，1本，小口切り，水にさらす\n" +
+		"\"塩,こしょう\",少々\n" +
+		"◎B\n" +
+		"◎砂糖…小さじ1\n" +
+		"バター 10g\n" +
+		"塩、こしょう\n" +
+		"==\n" +
+		"ピュアセレクト マヨネーズ\n"
+
+	want := []Line{
+		{LineNo: 1, Name: "玉ねぎ", Amount: "1個", Details: "みじん切り"},
+		{LineNo: 4, Group: "A", Name: "醤油", Amount: "大さじ2"},
+		{LineNo: 5, Group: "A", Name: "みりん", Amount: "大さじ1"},
+		{LineNo: 6, Group: "A", Name: "薄力粉", Amount: "1,000g"},
+		{LineNo: 7, Group: "A", Name: "鶏もも肉", Amount: "200g", Details: "皮なし，一口大"},
+		{LineNo: 8, Group: "A", Name: "水", Amount: "1,200ml", Details: "常温"},
+		{LineNo: 10, Group: "ソース", Name: "牛乳", Amount: "200ml", Details: "常温,よく振る"},
+		{LineNo: 11, Group: "ソース", Name: "鶏肉", Amount: "200g", Details: "皮なし: 1cm角"},
+		{LineNo: 12, Group: "ソース", Name: "ねぎ", Amount: "1本", Details: "小口切り，水にさらす"},
+		{LineNo: 13, Group: "ソース", Name: "塩,こしょう", Amount: "少々"},
+		{LineNo: 15, Group: "B", Name: "砂糖", Amount: "小さじ1"},
+		{LineNo: 16, Group: "B", Name: "バター", Amount: "10g"},
+		{LineNo: 17, Group: "B", Name: "塩、こしょう"},
+		{LineNo: 19, Name: "ピュアセレクト マヨネーズ"},
+	}
+
+	lines, errs := Parse(text)
+	if len(errs) != 0 {
+		t.Errorf("Parse errors = %v", errs)
+	}
+	if !reflect.DeepEqual(lines, want) {
+		t.Errorf("Parse =\n%+v\nwant\n%+v", lines, want)
+	}
+}
+
+func TestParseLineErrors(t *testing.T) {
+	text := "玉ねぎ,1個\n" +
+		"\"塩,少々\n" +
+		",200g\n" +
+		"砂糖,大さじ1\n"
+
+	lines, errs := Parse(text)
+	wantLines := []Line{
+		{LineNo: 1, Name: "玉ねぎ", Amount: "1個"},
+		{LineNo: 4, Name: "砂糖", Amount: "大さじ1"},
+	}
+	if !reflect.DeepEqual(lines, wantLines) {
+		t.Errorf("Parse lines = %+v, want %+v", lines, wantLines)
+	}
+	wantErrs := []LineError{
+		{LineNo: 2, Text: "\"塩,少々", Message: "「\"」の閉じ忘れがあります"},
+		{LineNo: 3, Text: ",200g", Message: "材料名がありません"},
+	}
+	if !reflect.DeepEqual(errs, wantErrs) {
+		t.Errorf("Parse errors = %+v, want %+v", errs, wantErrs)
+	}
+	if got, want := errs[0].Error(), "2行目: 「\"」の閉じ忘れがあります（\"塩,少々）"; got != want {
+		t.Errorf("LineError.Error() = %q, want %q", got, want)
+	}
+}
+
+func TestFormat(t *testing.T) {
+	lines := []Line{
+		{Name: "玉ねぎ", Amount: "1個", Details: "みじん切り"},
+		{Group: "A", Name: "醤油", Amount: "大さじ2"},
+		{Group: "A", Name: "塩,こしょう"},
+		{Group: "B", Name: "薄力粉", Amount: "1,000g"},
+		{Name: "水", Amount: "200ml"},
+	}
+	want := "玉ねぎ,1個,みじん切り\n" +
+		"=A=\n" +
+		"醤油,大さじ2\n" +
+		"\"塩,こしょう\"\n" +
+		"=B=\n" +
+		"薄力粉,\"1,000g\"\n" +
+		"==\n" +
+		"水,200ml"
+	got := Format(lines)
+	if got != want {
+		t.Fatalf("Format =\n%s\nwant\n%s", got, want)
+	}
+
+	// Format したものを Parse すると元に戻る
+	parsed, errs := Parse(got)
+	if len(errs) != 0 {
+		t.Fatalf("Parse(Format) errors = %v", errs)
+	}
+	for i := range parsed {
+		parsed[i].LineNo = 0
+	}
+	if !reflect.DeepEqual(parsed, lines) {
+		t.Errorf("Parse(Format) =\n%+v\nwant\n%+v", parsed, lines)
+	}
+}
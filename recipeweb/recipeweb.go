@@ -0,0 +1,327 @@
+// Package recipeweb はレシピサイトのページから schema.org の Recipe を読み取ります。
+//
+// 多くのレシピサイトは検索エンジン向けに <script type="application/ld+json"> で
+// Recipe を埋め込んでいるので、まずそれを探し、無ければ microdata（itemtype="…/Recipe"）を見ます。
+// 材料は「玉ねぎ 1個」のような1行ずつの文字列のまま返すので、列に分けるのは recipetext で行います。
+package recipeweb
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+var ErrNotFound = errors.New("ページにレシピ（schema.org の Recipe）が見つかりません")
+
+// Recipe はページから読み取ったレシピです。
+type Recipe struct {
+	Name         string   `json:"name"`
+	Yield        string   `json:"yield"`
+	Ingredients  []string `json:"ingredients"`  // recipeIngredient の1件が1行
+	Instructions []string `json:"instructions"` // 手順。HowToSection の名前は「【名前】」の行にする
+	URL          string   `json:"url"`
+	Source       string   `json:"source"` // json-ld / microdata
+}
+
+// IngredientText は材料を1行1件のテキストにします（recipetext.Parse に渡す形）。
+func (r *Recipe) IngredientText() string {
+	return strings.Join(r.Ingredients, "\n")
+}
+
+// ProcessText は手順を1行1手順のテキストにします。
+func (r *Recipe) ProcessText() string {
+	return strings.Join(r.Instructions, "\n")
+}
+
+// 読み込むページの大きさの上限
+const maxPageBytes = 5 << 20
+
+// Fetch はページを取得して Recipe を読み取ります。URL には取得したページの URL を入れます。
+func Fetch(client *http.Client, pageURL string) (*Recipe, error) {
+	req, err := http.NewRequest("GET", pageURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/91.0.4472.124 Safari/537.36")
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("ページを取得できませんでした: %s", resp.Status)
+	}
+
+	doc, err := goquery.NewDocumentFromReader(io.LimitReader(resp.Body, maxPageBytes))
+	if err != nil {
+		return nil, err
+	}
+	recipe, err := Extract(doc)
+	if err != nil {
+		return nil, err
+	}
+	recipe.URL = pageURL
+	return recipe, nil
+}
+
+// Extract は読み込み済みのページから Recipe を探します。JSON-LD を優先し、無ければ microdata を見ます。
+func Extract(doc *goquery.Document) (*Recipe, error) {
+	var found *Recipe
+	doc.Find(`script[type="application/ld+json"]`).EachWithBreak(func(_ int, s *goquery.Selection) bool {
+		var v interface{}
+		// 壊れた JSON-LD（末尾のカンマなど）を置いているサイトもあるので、読めないものは飛ばす
+		if err := json.Unmarshal([]byte(s.Text()), &v); err != nil {
+			return true
+		}
+		if m := findRecipeLD(v); m != nil {
+			found = recipeFromLD(m)
+			return false
+		}
+		return true
+	})
+	if found == nil {
+		found = recipeFromMicrodata(doc)
+	}
+	if found == nil || (found.Name == "" && len(found.Ingredients) == 0) {
+		return nil, ErrNotFound
+	}
+	return found, nil
+}
+
+// --- JSON-LD ---
+
+// Recipe の型を持つオブジェクトを探す。配列・@graph・mainEntity の中も見る
+func findRecipeLD(v interface{}) map[string]interface{} {
+	switch t := v.(type) {
+	case []interface{}:
+		for _, e := range t {
+			if m := findRecipeLD(e); m != nil {
+				return m
+			}
+		}
+	case map[string]interface{}:
+		if hasType(t["@type"], "Recipe") {
+			return t
+		}
+		for _, key := range []string{"@graph", "mainEntity"} {
+			if m := findRecipeLD(t[key]); m != nil {
+				return m
+			}
+		}
+	}
+	return nil
+}
+
+// @type は "Recipe" のほか ["Recipe", "NewsArticle"] や "schema:Recipe" のこともある
+func hasType(v interface{}, name string) bool {
+	switch t := v.(type) {
+	case string:
+		return t == name || strings.HasSuffix(t, ":"+name) || strings.HasSuffix(t, "/"+name)
+	case []interface{}:
+		for _, e := range t {
+			if hasType(e, name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func recipeFromLD(m map[string]interface{}) *Recipe {
+	r := &Recipe{Source: "json-ld", Name: textLD(m["name"]), Yield: yieldLD(m["recipeYield"])}
+	ingredients := m["recipeIngredient"]
+	if ingredients == nil {
+		ingredients = m["ingredients"] // 古い書き方
+	}
+	for _, e := range listLD(ingredients) {
+		if s := textLD(e); s != "" {
+			r.Ingredients = append(r.Ingredients, s)
+		}
+	}
+	r.Instructions = instructionsLD(m["recipeInstructions"])
+	return r
+}
+
+// 文字列・数値・{"name": ...} を文字列にする
+func textLD(v interface{}) string {
+	switch t := v.(type) {
+	case string:
+		return cleanText(t)
+	case float64:
+		return strconv.FormatFloat(t, 'f', -1, 64)
+	case map[string]interface{}:
+		for _, key := range []string{"text", "name", "@value"} {
+			if s := textLD(t[key]); s != "" {
+				return s
+			}
+		}
+	case []interface{}:
+		if len(t) > 0 {
+			return textLD(t[0])
+		}
+	}
+	return ""
+}
+
+func listLD(v interface{}) []interface{} {
+	switch t := v.(type) {
+	case nil:
+		return nil
+	case []interface{}:
+		return t
+	}
+	return []interface{}{v}
+}
+
+// recipeYield は "2人分"、4、["4", "4人分"] などいろいろなので、数字だけでないものを優先する
+func yieldLD(v interface{}) string {
+	first := ""
+	for _, e := range listLD(v) {
+		s := textLD(e)
+		if s == "" {
+			continue
+		}
+		if _, err := strconv.ParseFloat(s, 64); err != nil {
+			return s
+		}
+		if first == "" {
+			first = s
+		}
+	}
+	return first
+}
+
+// recipeInstructions は1つの文字列、文字列の配列、HowToStep の配列、HowToSection の入れ子のどれか
+func instructionsLD(v interface{}) []string {
+	var steps []string
+	for _, e := range listLD(v) {
+		switch t := e.(type) {
+		case string:
+			steps = append(steps, splitLines(t)...)
+		case map[string]interface{}:
+			if hasType(t["@type"], "HowToSection") {
+				if name := textLD(t["name"]); name != "" {
+					steps = append(steps, "【"+name+"】")
+				}
+				steps = append(steps, instructionsLD(t["itemListElement"])...)
+				continue
+			}
+			if text, ok := t["text"].(string); ok {
+				steps = append(steps, splitLines(text)...)
+			} else if s := textLD(t); s != "" {
+				steps = append(steps, s)
+			}
+		}
+	}
+	return steps
+}
+
+// <br> や段落の区切りで行に分ける
+func splitLines(s string) []string {
+	var lines []string
+	for _, line := range strings.Split(breakPattern.ReplaceAllString(s, "\n"), "\n") {
+		if line = cleanText(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// --- microdata ---
+
+func recipeFromMicrodata(doc *goquery.Document) *Recipe {
+	scope := doc.Find("[itemscope][itemtype]").FilterFunction(func(_ int, s *goquery.Selection) bool {
+		for _, t := range strings.Fields(s.AttrOr("itemtype", "")) {
+			if hasType(t, "Recipe") {
+				return true
+			}
+		}
+		return false
+	}).First()
+	if scope.Length() == 0 {
+		return nil
+	}
+
+	r := &Recipe{Source: "microdata"}
+	if s := itemProps(scope, "name").First(); s.Length() > 0 {
+		r.Name = propValue(s)
+	}
+	if s := itemProps(scope, "recipeYield").First(); s.Length() > 0 {
+		r.Yield = propValue(s)
+	}
+	ingredients := itemProps(scope, "recipeIngredient")
+	if ingredients.Length() == 0 {
+		ingredients = itemProps(scope, "ingredients")
+	}
+	ingredients.Each(func(_ int, s *goquery.Selection) {
+		if v := propValue(s); v != "" {
+			r.Ingredients = append(r.Ingredients, v)
+		}
+	})
+	itemProps(scope, "recipeInstructions").Each(func(_ int, s *goquery.Selection) {
+		if _, ok := s.Attr("itemscope"); ok {
+			// HowToStep の中の text
+			if t := itemProps(s, "text").First(); t.Length() > 0 {
+				s = t
+			}
+		}
+		for _, line := range strings.Split(blockText(s), "\n") {
+			if line = cleanText(line); line != "" {
+				r.Instructions = append(r.Instructions, line)
+			}
+		}
+	})
+	return r
+}
+
+// scope 直下の itemprop だけを返す（入れ子の itemscope、たとえば author の name は除く）
+func itemProps(scope *goquery.Selection, name string) *goquery.Selection {
+	root := scope.Get(0)
+	return scope.Find(fmt.Sprintf("[itemprop~=%q]", name)).FilterFunction(func(_ int, s *goquery.Selection) bool {
+		owner := s.ParentsFiltered("[itemscope]").First()
+		return owner.Length() > 0 && owner.Get(0) == root
+	})
+}
+
+// meta・link などは属性、それ以外は中の文字列が値
+func propValue(s *goquery.Selection) string {
+	if v, ok := s.Attr("content"); ok {
+		return cleanText(v)
+	}
+	switch goquery.NodeName(s) {
+	case "a", "link":
+		return cleanText(s.AttrOr("href", ""))
+	case "img":
+		return cleanText(s.AttrOr("src", ""))
+	}
+	return cleanText(s.Text())
+}
+
+// <br> と <p>・<li> の区切りを改行にして文字列を取り出す
+func blockText(s *goquery.Selection) string {
+	s = s.Clone()
+	s.Find("br").ReplaceWithHtml("\n")
+	s.Find("p, li, div").Each(func(_ int, e *goquery.Selection) {
+		e.AppendHtml("\n")
+	})
+	return s.Text()
+}
+
+var (
+	tagPattern   = regexp.MustCompile(`<[^>]*>`)
+	breakPattern = regexp.MustCompile(`(?i)<br\s*/?>|</p>|</li>`)
+)
+
+// 文字参照を戻し、タグを外し、連続する空白（全角スペースを含む）を1つにする
+func cleanText(s string) string {
+	s = html.UnescapeString(tagPattern.ReplaceAllString(s, ""))
+	return strings.Join(strings.Fields(s), " ")
+}
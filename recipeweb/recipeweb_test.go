@@ -0,0 +1,172 @@
+package recipeweb
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// テスト用のレシピサイト。パスごとにページを返す
+var fixtures = map[string]string{
+	"/graph": `<html><head>
+<script type="application/ld+json">
+{"@context": "https://schema.org", "@graph": [
+  {"@type": "WebPage", "name": "ページ"},
+  {"@type": ["Recipe", "NewsArticle"], "name": "肉じゃが", "recipeYield": ["4", "4人分"],
+   "recipeIngredient": ["じゃがいも 3個", "牛肉&amp;豚肉 200g"],
+   "recipeInstructions": [
+     {"@type": "HowToStep", "text": "じゃがいもを<b>切る</b>。"},
+     {"@type": "HowToStep", "text": "煮る。<br>味をしみこませる。"}
+   ]}
+]}
+</script></head><body></body></html>`,
+
+	"/array": `<html><head>
+<script type="application/ld+json">
+[{"@type": "BreadcrumbList"}, {"@type": "Recipe", "name": "味噌汁", "recipeYield": 2,
+  "recipeIngredient": ["豆腐 1丁", "味噌 大さじ2"], "recipeInstructions": "だしを取る。\n味噌を溶く。"}]
+</script></head><body></body></html>`,
+
+	"/section": `<html><head>
+<script type="application/ld+json">
+{"@type": "Recipe", "name": "ハンバーグ", "recipeIngredient": ["合いびき肉 300g"],
+ "recipeInstructions": [
+   {"@type": "HowToSection", "name": "ソース", "itemListElement": [
+     {"@type": "HowToStep", "text": "ケチャップとソースを混ぜる。"}]},
+   {"@type": "HowToSection", "name": "焼く", "itemListElement": [
+     {"@type": "HowToStep", "text": "両面を焼く。"}, {"@type": "HowToStep", "text": "蓋をして蒸す。"}]}
+ ]}
+</script></head><body></body></html>`,
+
+	"/microdata": `<html><body>
+<div itemscope itemtype="https://schema.org/Recipe">
+  <h1 itemprop="name">親子丼</h1>
+  <meta itemprop="recipeYield" content="2人分">
+  <div itemprop="author" itemscope itemtype="https://schema.org/Person"><span itemprop="name">山田</span></div>
+  <ul>
+    <li itemprop="recipeIngredient">鶏もも肉　1枚</li>
+    <li itemprop="recipeIngredient">卵 3個</li>
+  </ul>
+  <div itemprop="recipeInstructions"><p>鶏肉を煮る。</p><p>卵でとじる。</p></div>
+</div>
+</body></html>`,
+
+	// 壊れた JSON-LD は飛ばして、次の JSON-LD を使う
+	"/broken": `<html><head>
+<script type="application/ld+json">{"@type": "Recipe", "name": "壊れている",}</script>
+<script type="application/ld+json">{"@type": "Recipe", "name": "卵焼き", "recipeIngredient": ["卵 2個"]}</script>
+</head><body></body></html>`,
+
+	// 壊れた JSON-LD しか無ければ microdata を見る
+	"/broken-microdata": `<html><head>
+<script type="application/ld+json">{"@type": "Recipe", "name": </script>
+</head><body>
+<div itemscope itemtype="http://schema.org/Recipe"><span itemprop="name">おにぎり</span>
+<span itemprop="ingredients">ご飯 150g</span></div>
+</body></html>`,
+
+	"/none": `<html><head><title>ブログ</title></head><body><p>今日の晩ごはん</p></body></html>`,
+}
+
+func newFixtureServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page, ok := fixtures[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(page))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestFetch(t *testing.T) {
+	srv := newFixtureServer(t)
+
+	tests := []struct {
+		path string
+		want Recipe
+	}{
+		{"/graph", Recipe{
+			Name:         "肉じゃが",
+			Yield:        "4人分",
+			Ingredients:  []string{"じゃがいも 3個", "牛肉&豚肉 200g"},
+			Instructions: []string{"じゃがいもを切る。", "煮る。", "味をしみこませる。"},
+			Source:       "json-ld",
+		}},
+		{"/array", Recipe{
+			Name:         "味噌汁",
+			Yield:        "2",
+			Ingredients:  []string{"豆腐 1丁", "味噌 大さじ2"},
+			Instructions: []string{"だしを取る。", "味噌を溶く。"},
+			Source:       "json-ld",
+		}},
+		{"/section", Recipe{
+			Name:         "ハンバーグ",
+			Ingredients:  []string{"合いびき肉 300g"},
+			Instructions: []string{"【ソース】", "ケチャップとソースを混ぜる。", "【焼く】", "両面を焼く。", "蓋をして蒸す。"},
+			Source:       "json-ld",
+		}},
+		{"/microdata", Recipe{
+			Name:         "親子丼",
+			Yield:        "2人分",
+			Ingredients:  []string{"鶏もも肉 1枚", "卵 3個"},
+			Instructions: []string{"鶏肉を煮る。", "卵でとじる。"},
+			Source:       "microdata",
+		}},
+		{"/broken", Recipe{
+			Name:        "卵焼き",
+			Ingredients: []string{"卵 2個"},
+			Source:      "json-ld",
+		}},
+		{"/broken-microdata", Recipe{
+			Name:        "おにぎり",
+			Ingredients: []string{"ご飯 150g"},
+			Source:      "microdata",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got, err := Fetch(srv.Client(), srv.URL+tt.path)
+			if err != nil {
+				t.Fatalf("Fetch: %v", err)
+			}
+			want := tt.want
+			want.URL = srv.URL + tt.path
+			if !reflect.DeepEqual(*got, want) {
+				t.Errorf("Fetch =\n%#v\nwant\n%#v", *got, want)
+			}
+		})
+	}
+}
+
+func TestFetchNotFound(t *testing.T) {
+	srv := newFixtureServer(t)
+	if _, err := Fetch(srv.Client(), srv.URL+"/none"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Fetch(/none) error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestFetchNon200(t *testing.T) {
+	srv := newFixtureServer(t)
+	_, err := Fetch(srv.Client(), srv.URL+"/missing")
+	if err == nil || errors.Is(err, ErrNotFound) || !strings.Contains(err.Error(), "404") {
+		t.Errorf("Fetch(/missing) error = %v, want a 404 error", err)
+	}
+}
+
+func TestRecipeText(t *testing.T) {
+	r := &Recipe{Ingredients: []string{"玉ねぎ 1個", "塩 少々"}, Instructions: []string{"切る。", "炒める。"}}
+	if got, want := r.IngredientText(), "玉ねぎ 1個\n塩 少々"; got != want {
+		t.Errorf("IngredientText = %q, want %q", got, want)
+	}
+	if got, want := r.ProcessText(), "切る。\n炒める。"; got != want {
+		t.Errorf("ProcessText = %q, want %q", got, want)
+	}
+}
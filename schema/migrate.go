@@ -0,0 +1,233 @@
+// Package schema はサーバーと tools/ 以下の全ツールで共有する
+// SQLite スキーマのバージョン管理（マイグレーション）を提供します。
+//
+// 適用済みのバージョンは schema_migrations テーブルに記録され、
+// 未適用のマイグレーションだけが番号順に1件ずつトランザクション内で実行されます。
+// 失敗した場合はロールバックしてエラーを返します（握りつぶしません）。
+package schema
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+)
+
+// Migration は1つのスキーマ変更です。Version は 1 から始まる連番です。
+type Migration struct {
+	Version int
+	Name    string
+	Up      func(tx *sql.Tx) error
+}
+
+// MigrationStatus は migrate status 表示用の1行分の情報です。
+type MigrationStatus struct {
+	Version   int
+	Name      string
+	Applied   bool
+	AppliedAt string
+}
+
+const createMigrationsTableSQL = `
+CREATE TABLE IF NOT EXISTS schema_migrations (
+	version INTEGER PRIMARY KEY,
+	name TEXT NOT NULL,
+	applied_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);`
+
+// Latest はこのバイナリが知っている最新のスキーマバージョンです。
+func Latest() int {
+	if len(migrations) == 0 {
+		return 0
+	}
+	return migrations[len(migrations)-1].Version
+}
+
+// CurrentVersion は DB に適用済みの最大バージョンを返します（未管理なら 0）。
+// DB には何も書き込みません。
+func CurrentVersion(db *sql.DB) (int, error) {
+	managed, err := isManaged(db)
+	if err != nil || !managed {
+		return 0, err
+	}
+	var v int
+	if err := db.QueryRow("SELECT COALESCE(MAX(version), 0) FROM schema_migrations").Scan(&v); err != nil {
+		return 0, err
+	}
+	return v, nil
+}
+
+// Migrate は未適用のマイグレーションを番号順に全て適用し、適用した件数を返します。
+func Migrate(db *sql.DB) (int, error) {
+	if err := validate(); err != nil {
+		return 0, err
+	}
+	if _, err := db.Exec(createMigrationsTableSQL); err != nil {
+		return 0, fmt.Errorf("schema_migrations error: %w", err)
+	}
+	current, err := CurrentVersion(db)
+	if err != nil {
+		return 0, err
+	}
+	if current > Latest() {
+		return 0, fmt.Errorf("DBのスキーマ(v%d)がこのプログラムの対応バージョン(v%d)より新しいです", current, Latest())
+	}
+
+	applied := 0
+	for _, m := range migrations {
+		if m.Version <= current {
+			continue
+		}
+		if err := apply(db, m); err != nil {
+			return applied, err
+		}
+		applied++
+	}
+	return applied, nil
+}
+
+// Status は全マイグレーションの適用状況を返します。
+func Status(db *sql.DB) ([]MigrationStatus, error) {
+	appliedAt := make(map[int]string)
+	managed, err := isManaged(db)
+	if err != nil {
+		return nil, err
+	}
+	if managed {
+		rows, err := db.Query("SELECT version, applied_at FROM schema_migrations")
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var v int
+			var at sql.NullString
+			if err := rows.Scan(&v, &at); err != nil {
+				return nil, err
+			}
+			appliedAt[v] = at.String
+		}
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	var list []MigrationStatus
+	for _, m := range migrations {
+		at, ok := appliedAt[m.Version]
+		list = append(list, MigrationStatus{Version: m.Version, Name: m.Name, Applied: ok, AppliedAt: at})
+	}
+	return list, nil
+}
+
+// DSN は DB ファイルのパスに接続オプションを付けます。
+// 外部キー制約（foreign_keys）は接続ごとの設定なので、PRAGMA を1回流すのではなく
+// DSN に書いてプール内の全接続で有効にします。
+func DSN(path string) string {
+	return path + "?_foreign_keys=on"
+}
+
+// ForeignKeyViolations は外部キー制約に違反している行数をテーブルごとに返します。
+// 制約を有効にする前に作られた孤立行の確認用です（DB には何も書き込みません）。
+func ForeignKeyViolations(db *sql.DB) (map[string]int, error) {
+	rows, err := db.Query("PRAGMA foreign_key_check")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int)
+	for rows.Next() {
+		var table, parent string
+		var rowid sql.NullInt64
+		var fkid int
+		if err := rows.Scan(&table, &rowid, &parent, &fkid); err != nil {
+			return nil, err
+		}
+		counts[table+" → "+parent]++
+	}
+	return counts, rows.Err()
+}
+
+// isManaged は schema_migrations テーブルが既にあるかを返します。
+func isManaged(db *sql.DB) (bool, error) {
+	var n int
+	err := db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'schema_migrations'").Scan(&n)
+	return n > 0, err
+}
+
+func apply(db *sql.DB, m Migration) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	if err := m.Up(tx); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("migration %03d (%s) failed: %w", m.Version, m.Name, err)
+	}
+	if _, err := tx.Exec("INSERT INTO schema_migrations(version, name) VALUES(?, ?)", m.Version, m.Name); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("migration %03d (%s) record failed: %w", m.Version, m.Name, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("migration %03d (%s) commit failed: %w", m.Version, m.Name, err)
+	}
+	return nil
+}
+
+// validate はマイグレーション定義が 1 からの連番で並んでいることを確認します。
+func validate() error {
+	if !sort.SliceIsSorted(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version }) {
+		return fmt.Errorf("migrations are not sorted by version")
+	}
+	for i, m := range migrations {
+		if m.Version != i+1 {
+			return fmt.Errorf("migration version gap: expected %d, got %d (%s)", i+1, m.Version, m.Name)
+		}
+	}
+	return nil
+}
+
+// execAll は複数の SQL を順に実行し、最初のエラーで止まります。
+func execAll(tx *sql.Tx, stmts ...string) error {
+	for _, q := range stmts {
+		if _, err := tx.Exec(q); err != nil {
+			return fmt.Errorf("%w\n%s", err, q)
+		}
+	}
+	return nil
+}
+
+// hasColumn はテーブルに指定カラムが存在するかを PRAGMA table_info で調べます。
+func hasColumn(tx *sql.Tx, table, column string) (bool, error) {
+	rows, err := tx.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var cid, notNull, pk int
+		var name, typ string
+		var dflt sql.NullString
+		if err := rows.Scan(&cid, &name, &typ, &notNull, &dflt, &pk); err != nil {
+			return false, err
+		}
+		if name == column {
+			return true, nil
+		}
+	}
+	return false, rows.Err()
+}
+
+// addColumn はカラムが無い場合だけ ALTER TABLE ... ADD COLUMN を実行します。
+// schema_migrations 導入前の DB は列の有無がまちまちなので、その吸収に使います。
+func addColumn(tx *sql.Tx, table, column, definition string) error {
+	ok, err := hasColumn(tx, table, column)
+	if err != nil {
+		return err
+	}
+	if ok {
+		return nil
+	}
+	_, err = tx.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition))
+	return err
+}
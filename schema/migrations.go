@@ -0,0 +1,474 @@
+package schema
+
+import "database/sql"
+
+// migrations は適用順に並んだスキーマ変更の一覧です。
+// 一度リリースしたマイグレーションは書き換えず、変更は必ず末尾に追加してください。
+var migrations = []Migration{
+	{1, "initial schema", migrateInitialSchema},
+	{2, "locations table", migrateLocations},
+	{3, "location_id foreign keys", migrateLocationIDs},
+	{4, "users and sessions", migrateUsers},
+	{5, "audit log", migrateAuditLog},
+	{6, "catalog merges", migrateCatalogMerges},
+	{7, "ingredient events", migrateIngredientEvents},
+	{8, "waste reasons", migrateWasteReasons},
+	{9, "shelf life", migrateShelfLife},
+	{10, "shopping list", migrateShoppingList},
+	{11, "structured recipe quantities", migrateRecipeQuantities},
+	{12, "unit conversion factors", migrateConversionFactors},
+	{13, "cook log", migrateCookLog},
+	{14, "recipe trash", migrateRecipeTrash},
+	{15, "recipe search", migrateRecipeSearch},
+	{16, "item aliases", migrateItemAliases},
+}
+
+// 001: schema_migrations 導入前の initDatabase / fixDatabaseSchema 相当。
+// 既存 DB に対しても安全なように IF NOT EXISTS と addColumn で書いています。
+func migrateInitialSchema(tx *sql.Tx) error {
+	err := execAll(tx,
+		`CREATE TABLE IF NOT EXISTS item_catalog (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			name TEXT NOT NULL UNIQUE,
+			kana TEXT,
+			classification TEXT NOT NULL,
+			category TEXT,
+			default_unit TEXT
+		);`,
+		`CREATE TABLE IF NOT EXISTS refrigerator_ingredients (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			catalog_id INTEGER NOT NULL,
+			amount REAL,
+			unit TEXT,
+			expiration_date TEXT,
+			location TEXT,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (catalog_id) REFERENCES item_catalog (id)
+		);`,
+		`CREATE TABLE IF NOT EXISTS refrigerator_seasonings (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			catalog_id INTEGER NOT NULL,
+			status TEXT NOT NULL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (catalog_id) REFERENCES item_catalog (id)
+		);`,
+		`CREATE TABLE IF NOT EXISTS recipes (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			name TEXT NOT NULL UNIQUE,
+			yield TEXT,
+			process TEXT,
+			url TEXT,
+			original_ingredients TEXT DEFAULT '',
+			original_process TEXT DEFAULT '',
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		);`,
+		`CREATE TABLE IF NOT EXISTS recipe_ingredients (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			recipe_id INTEGER NOT NULL,
+			catalog_id INTEGER NOT NULL,
+			unit TEXT,
+			amount TEXT,
+			group_name TEXT,
+			details TEXT DEFAULT '',
+			FOREIGN KEY (recipe_id) REFERENCES recipes (id),
+			FOREIGN KEY (catalog_id) REFERENCES item_catalog (id)
+		);`,
+		`CREATE TABLE IF NOT EXISTS fridge_photos (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			image_path TEXT NOT NULL,
+			location TEXT,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		);`,
+	)
+	if err != nil {
+		return err
+	}
+
+	// 古い DB で後から追加されたカラム
+	columns := []struct{ table, column, definition string }{
+		{"item_catalog", "kana", "TEXT"},
+		{"refrigerator_ingredients", "location", "TEXT"},
+		{"recipes", "yield", "TEXT"},
+		{"recipes", "original_ingredients", "TEXT DEFAULT ''"},
+		{"recipes", "original_process", "TEXT DEFAULT ''"},
+		{"recipe_ingredients", "unit", "TEXT"},
+		{"recipe_ingredients", "group_name", "TEXT"},
+		{"recipe_ingredients", "details", "TEXT DEFAULT ''"},
+		{"fridge_photos", "location", "TEXT"},
+	}
+	for _, c := range columns {
+		if err := addColumn(tx, c.table, c.column, c.definition); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 002: 保管場所マスタ。既定の場所に加え、在庫・写真で既に使われている場所名も登録する。
+func migrateLocations(tx *sql.Tx) error {
+	err := execAll(tx,
+		`CREATE TABLE IF NOT EXISTS locations (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			name TEXT NOT NULL UNIQUE,
+			priority INTEGER NOT NULL DEFAULT 0
+		);`,
+		// 手作業で作られた UNIQUE 無しの locations にも制約を付ける
+		`CREATE UNIQUE INDEX IF NOT EXISTS idx_locations_name ON locations (name);`,
+	)
+	if err != nil {
+		return err
+	}
+
+	defaults := []string{"冷蔵庫", "冷凍庫", "野菜室", "常温", "その他"}
+	for i, name := range defaults {
+		if _, err := tx.Exec("INSERT OR IGNORE INTO locations(name, priority) VALUES(?, ?)", name, i+1); err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.Exec(`
+		INSERT OR IGNORE INTO locations(name, priority)
+		SELECT name, (SELECT COALESCE(MAX(priority), 0) FROM locations) + ROW_NUMBER() OVER (ORDER BY name)
+		FROM (
+			SELECT location AS name FROM refrigerator_ingredients WHERE location IS NOT NULL AND location != ''
+			UNION
+			SELECT location AS name FROM fridge_photos WHERE location IS NOT NULL AND location != ''
+		)
+	`)
+	return err
+}
+
+// 003: 在庫・写真の保管場所を自由記述から locations.id への参照に置き換える。
+// 既存の文字列は同名の場所に対応付け（無ければ作成）、空欄は「その他」とする。
+func migrateLocationIDs(tx *sql.Tx) error {
+	for _, table := range []string{"refrigerator_ingredients", "fridge_photos"} {
+		if err := addColumn(tx, table, "location_id", "INTEGER REFERENCES locations (id)"); err != nil {
+			return err
+		}
+	}
+
+	err := execAll(tx,
+		`INSERT OR IGNORE INTO locations(name, priority)
+		 SELECT 'その他', COALESCE(MAX(priority), 0) + 1 FROM locations;`,
+		`UPDATE refrigerator_ingredients SET location = 'その他' WHERE location IS NULL OR TRIM(location) = '';`,
+		`UPDATE fridge_photos SET location = 'その他' WHERE location IS NULL OR TRIM(location) = '';`,
+		`INSERT OR IGNORE INTO locations(name, priority)
+		 SELECT name, (SELECT COALESCE(MAX(priority), 0) FROM locations) + ROW_NUMBER() OVER (ORDER BY name)
+		 FROM (
+			SELECT location AS name FROM refrigerator_ingredients
+			UNION
+			SELECT location AS name FROM fridge_photos
+		 );`,
+		`UPDATE refrigerator_ingredients SET location_id = (SELECT id FROM locations WHERE name = refrigerator_ingredients.location);`,
+		`UPDATE fridge_photos SET location_id = (SELECT id FROM locations WHERE name = fridge_photos.location);`,
+		`ALTER TABLE refrigerator_ingredients DROP COLUMN location;`,
+		`ALTER TABLE fridge_photos DROP COLUMN location;`,
+	)
+	return err
+}
+
+// 004: ログインユーザーとセッション（パスワードは bcrypt ハッシュ、トークンは SHA-256 で保存）
+func migrateUsers(tx *sql.Tx) error {
+	return execAll(tx,
+		`CREATE TABLE IF NOT EXISTS users (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			username TEXT NOT NULL UNIQUE,
+			password_hash TEXT NOT NULL,
+			is_admin INTEGER NOT NULL DEFAULT 0,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		);`,
+		`CREATE TABLE IF NOT EXISTS sessions (
+			token_hash TEXT PRIMARY KEY,
+			user_id INTEGER NOT NULL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			expires_at DATETIME NOT NULL,
+			FOREIGN KEY (user_id) REFERENCES users (id)
+		);`,
+		`CREATE INDEX IF NOT EXISTS idx_sessions_user ON sessions (user_id);`,
+	)
+}
+
+// 005: 在庫・カタログ・レシピの変更履歴（変更前後のスナップショットを JSON で保存）
+func migrateAuditLog(tx *sql.Tx) error {
+	return execAll(tx,
+		`CREATE TABLE IF NOT EXISTS audit_log (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER,
+			actor TEXT NOT NULL,
+			entity_type TEXT NOT NULL,
+			entity_id INTEGER NOT NULL,
+			action TEXT NOT NULL,
+			before_json TEXT,
+			after_json TEXT,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (user_id) REFERENCES users (id)
+		);`,
+		`CREATE INDEX IF NOT EXISTS idx_audit_entity ON audit_log (entity_type, entity_id);`,
+		`CREATE INDEX IF NOT EXISTS idx_audit_created ON audit_log (created_at);`,
+	)
+}
+
+// 006: カタログ統合の記録。統合元の行と、付け替えた行の id を保存して取り消せるようにする
+func migrateCatalogMerges(tx *sql.Tx) error {
+	return execAll(tx,
+		`CREATE TABLE IF NOT EXISTS catalog_merges (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			source_id INTEGER NOT NULL,
+			target_id INTEGER NOT NULL,
+			source_snapshot TEXT NOT NULL,
+			moved_rows TEXT NOT NULL,
+			deleted_rows TEXT NOT NULL DEFAULT '{}',
+			merged_by TEXT,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			reverted_at DATETIME,
+			reverted_by TEXT
+		);`,
+	)
+}
+
+// 007: 在庫の出入り履歴（追加・使用・廃棄・移動）
+func migrateIngredientEvents(tx *sql.Tx) error {
+	return execAll(tx,
+		`CREATE TABLE IF NOT EXISTS ingredient_events (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			ingredient_id INTEGER NOT NULL,
+			catalog_id INTEGER NOT NULL,
+			event_type TEXT NOT NULL,
+			amount REAL NOT NULL DEFAULT 0,
+			unit TEXT,
+			location_id INTEGER,
+			from_location_id INTEGER,
+			note TEXT,
+			actor TEXT,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (catalog_id) REFERENCES item_catalog (id),
+			FOREIGN KEY (location_id) REFERENCES locations (id),
+			FOREIGN KEY (from_location_id) REFERENCES locations (id)
+		);`,
+		`CREATE INDEX IF NOT EXISTS idx_ingredient_events_catalog ON ingredient_events (catalog_id, created_at);`,
+	)
+}
+
+// 008: 削除理由と、廃棄時点の賞味期限（フードロス集計用）
+func migrateWasteReasons(tx *sql.Tx) error {
+	if err := addColumn(tx, "ingredient_events", "reason", "TEXT"); err != nil {
+		return err
+	}
+	if err := addColumn(tx, "ingredient_events", "expiration_date", "TEXT"); err != nil {
+		return err
+	}
+	return execAll(tx,
+		`CREATE INDEX IF NOT EXISTS idx_ingredient_events_type ON ingredient_events (event_type, created_at);`,
+	)
+}
+
+// 009: 日持ち日数（カタログ項目ごと・カテゴリごと）と、場所の保存方法
+// storage_type は fridge（冷蔵）/ freezer（冷凍）/ room（常温）
+func migrateShelfLife(tx *sql.Tx) error {
+	if err := addColumn(tx, "locations", "storage_type", "TEXT NOT NULL DEFAULT 'fridge'"); err != nil {
+		return err
+	}
+	for _, col := range []string{"shelf_life_fridge", "shelf_life_freezer", "shelf_life_room"} {
+		if err := addColumn(tx, "item_catalog", col, "INTEGER"); err != nil {
+			return err
+		}
+	}
+	err := execAll(tx,
+		`UPDATE locations SET storage_type = 'freezer' WHERE name LIKE '%冷凍%';`,
+		`UPDATE locations SET storage_type = 'room' WHERE name LIKE '%常温%';`,
+		`CREATE TABLE IF NOT EXISTS category_shelf_life (
+			category TEXT NOT NULL,
+			storage_type TEXT NOT NULL,
+			days INTEGER NOT NULL,
+			PRIMARY KEY (category, storage_type)
+		);`,
+	)
+	if err != nil {
+		return err
+	}
+
+	// カテゴリごとの目安（seeds/master_data.csv のカテゴリに合わせる）
+	defaults := []struct {
+		category              string
+		fridge, freezer, room int
+	}{
+		{"野菜", 7, 30, 3},
+		{"きのこ", 5, 30, 0},
+		{"肉", 3, 30, 0},
+		{"肉加工品", 7, 30, 0},
+		{"魚介", 2, 30, 0},
+		{"卵・乳製品", 7, 30, 0},
+		{"大豆製品", 5, 30, 0},
+		{"麺類", 3, 30, 0},
+		{"パン", 5, 30, 3},
+		{"穀物", 0, 0, 180},
+		{"乾物・粉類", 180, 0, 180},
+		{"缶詰", 0, 0, 365},
+	}
+	for _, d := range defaults {
+		for storage, days := range map[string]int{"fridge": d.fridge, "freezer": d.freezer, "room": d.room} {
+			if days == 0 {
+				continue
+			}
+			if _, err := tx.Exec("INSERT OR IGNORE INTO category_shelf_life(category, storage_type, days) VALUES(?, ?, ?)", d.category, storage, days); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// 010: 買い物リスト（recipe_id はレシピから追加したときの元レシピ）
+func migrateShoppingList(tx *sql.Tx) error {
+	return execAll(tx,
+		`CREATE TABLE IF NOT EXISTS shopping_list (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			catalog_id INTEGER NOT NULL,
+			quantity TEXT,
+			unit TEXT,
+			note TEXT,
+			recipe_id INTEGER,
+			checked INTEGER NOT NULL DEFAULT 0,
+			created_by TEXT,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (catalog_id) REFERENCES item_catalog (id),
+			FOREIGN KEY (recipe_id) REFERENCES recipes (id)
+		);`,
+	)
+}
+
+// 011: 分量文字列（amount）を quantity パッケージで分解した値。amount はそのまま残す。
+// 既存の行は tools/quantity_backfill で埋める
+func migrateRecipeQuantities(tx *sql.Tx) error {
+	for _, col := range []struct{ name, def string }{
+		{"quantity_value", "REAL"},
+		{"quantity_max", "REAL"},
+		{"quantity_unit", "TEXT"},
+		{"quantity_qualifier", "TEXT"},
+	} {
+		if err := addColumn(tx, "recipe_ingredients", col.name, col.def); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 012: 単位換算用の係数。density は 1ml あたりの g、piece_grams は piece_unit 1つあたりの g
+func migrateConversionFactors(tx *sql.Tx) error {
+	for _, col := range []struct{ name, def string }{
+		{"density", "REAL"},
+		{"piece_unit", "TEXT"},
+		{"piece_grams", "REAL"},
+	} {
+		if err := addColumn(tx, "item_catalog", col.name, col.def); err != nil {
+			return err
+		}
+	}
+
+	// よく使う調味料の密度（大さじ1杯の重さ ÷ 15ml）
+	densities := map[string]float64{
+		"醤油": 1.2, "みりん": 1.2, "料理酒": 1.0, "酢": 1.0, "塩": 1.2, "砂糖": 0.6,
+		"みそ": 1.2, "マヨネーズ": 0.8, "ケチャップ": 1.0, "ソース": 1.2, "オイスターソース": 1.2,
+		"小麦粉": 0.6, "片栗粉": 0.6, "パン粉": 0.2, "牛乳": 1.0, "生クリーム": 1.0, "バター": 0.8,
+		"米": 0.83,
+	}
+	for name, d := range densities {
+		if _, err := tx.Exec("UPDATE item_catalog SET density = ? WHERE name = ? AND density IS NULL", d, name); err != nil {
+			return err
+		}
+	}
+
+	// 1つあたりの重さの目安
+	pieces := []struct {
+		name, unit string
+		grams      float64
+	}{
+		{"玉ねぎ", "個", 200}, {"じゃがいも", "個", 150}, {"にんじん", "本", 150}, {"キャベツ", "個", 1200},
+		{"トマト", "個", 150}, {"なす", "本", 80}, {"きゅうり", "本", 100}, {"ピーマン", "個", 35},
+		{"大根", "本", 1000}, {"長ねぎ", "本", 100}, {"にんにく", "かけ", 5}, {"生姜", "かけ", 15},
+		{"卵", "個", 60}, {"豆腐", "丁", 300}, {"油揚げ", "枚", 30}, {"ウインナー", "本", 20},
+		{"食パン", "枚", 60}, {"鮭", "切れ", 80}, {"もやし", "袋", 200}, {"しめじ", "パック", 100},
+	}
+	for _, p := range pieces {
+		if _, err := tx.Exec("UPDATE item_catalog SET piece_unit = ?, piece_grams = ? WHERE name = ? AND piece_unit IS NULL",
+			p.unit, p.grams, p.name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 013: 作った料理の記録。レシピが消えても残るよう名前も持っておく
+func migrateCookLog(tx *sql.Tx) error {
+	return execAll(tx,
+		`CREATE TABLE IF NOT EXISTS cook_log (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			recipe_id INTEGER,
+			recipe_name TEXT NOT NULL,
+			servings REAL,
+			notes TEXT,
+			cooked_on TEXT NOT NULL,
+			created_by TEXT,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (recipe_id) REFERENCES recipes (id)
+		);`,
+		`CREATE INDEX IF NOT EXISTS idx_cook_log_recipe ON cook_log (recipe_id, cooked_on);`,
+	)
+}
+
+// 014: レシピのゴミ箱。deleted_at が入っているレシピは一覧などから除外し、復元できるようにする
+func migrateRecipeTrash(tx *sql.Tx) error {
+	return addColumn(tx, "recipes", "deleted_at", "DATETIME")
+}
+
+// 015: レシピ検索の索引を作り直すべきレシピの一覧。
+// 索引（FTS5 の仮想テーブル）は FTS5 入りでビルドしたときだけ起動時に作るので、ここでは変更の印だけをトリガーで付ける
+func migrateRecipeSearch(tx *sql.Tx) error {
+	return execAll(tx,
+		`CREATE TABLE IF NOT EXISTS recipe_search_dirty (
+			recipe_id INTEGER PRIMARY KEY
+		);`,
+		`CREATE TRIGGER IF NOT EXISTS trg_recipe_search_recipes_insert AFTER INSERT ON recipes BEGIN
+			INSERT OR IGNORE INTO recipe_search_dirty (recipe_id) VALUES (NEW.id);
+		END;`,
+		`CREATE TRIGGER IF NOT EXISTS trg_recipe_search_recipes_update AFTER UPDATE ON recipes BEGIN
+			INSERT OR IGNORE INTO recipe_search_dirty (recipe_id) VALUES (NEW.id);
+		END;`,
+		`CREATE TRIGGER IF NOT EXISTS trg_recipe_search_recipes_delete AFTER DELETE ON recipes BEGIN
+			INSERT OR IGNORE INTO recipe_search_dirty (recipe_id) VALUES (OLD.id);
+		END;`,
+		`CREATE TRIGGER IF NOT EXISTS trg_recipe_search_ingredients_insert AFTER INSERT ON recipe_ingredients BEGIN
+			INSERT OR IGNORE INTO recipe_search_dirty (recipe_id) VALUES (NEW.recipe_id);
+		END;`,
+		`CREATE TRIGGER IF NOT EXISTS trg_recipe_search_ingredients_update AFTER UPDATE ON recipe_ingredients BEGIN
+			INSERT OR IGNORE INTO recipe_search_dirty (recipe_id) VALUES (OLD.recipe_id);
+			INSERT OR IGNORE INTO recipe_search_dirty (recipe_id) VALUES (NEW.recipe_id);
+		END;`,
+		`CREATE TRIGGER IF NOT EXISTS trg_recipe_search_ingredients_delete AFTER DELETE ON recipe_ingredients BEGIN
+			INSERT OR IGNORE INTO recipe_search_dirty (recipe_id) VALUES (OLD.recipe_id);
+		END;`,
+		`CREATE TRIGGER IF NOT EXISTS trg_recipe_search_catalog_update AFTER UPDATE OF name, kana ON item_catalog BEGIN
+			INSERT OR IGNORE INTO recipe_search_dirty (recipe_id)
+				SELECT DISTINCT recipe_id FROM recipe_ingredients WHERE catalog_id = NEW.id;
+		END;`,
+		`INSERT OR IGNORE INTO recipe_search_dirty (recipe_id) SELECT id FROM recipes;`,
+	)
+}
+
+// 016: カタログの別名（「小ねぎ」→「青ネギ」）。details はその別名で書かれた材料に付ける既定の詳細（「粉末」など）
+func migrateItemAliases(tx *sql.Tx) error {
+	return execAll(tx,
+		`CREATE TABLE IF NOT EXISTS item_aliases (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			alias TEXT NOT NULL UNIQUE,
+			catalog_id INTEGER NOT NULL,
+			details TEXT DEFAULT '',
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (catalog_id) REFERENCES item_catalog (id)
+		);`,
+		`CREATE INDEX IF NOT EXISTS idx_item_aliases_catalog ON item_aliases (catalog_id);`,
+	)
+}
@@ -1,3 +1,14 @@
+// セッション切れ (401) になったらログイン画面へ
+const originalFetch = window.fetch;
+window.fetch = function(...args) {
+    return originalFetch.apply(this, args).then(res => {
+        if (res.status === 401) {
+            location.href = '/login.html';
+        }
+        return res;
+    });
+};
+
 document.addEventListener('DOMContentLoaded', () => {
     const appContainer = document.getElementById('app-container');
     const navItems = document.querySelectorAll('.nav-item');
@@ -127,6 +127,12 @@ function resetModal(titleText) {
     document.getElementById('input-name').value = '';
     document.getElementById('input-kana').value = '';
     document.getElementById('input-unit').value = '';
+    document.getElementById('input-shelf-fridge').value = '';
+    document.getElementById('input-shelf-freezer').value = '';
+    document.getElementById('input-shelf-room').value = '';
+    document.getElementById('input-piece-unit').value = '';
+    document.getElementById('input-piece-grams').value = '';
+    document.getElementById('input-density').value = '';
     document.getElementById('input-csv-text').value = '';
     document.getElementById('reference-area').style.display = 'none';
     document.getElementById('csv-result-area').style.display = 'none';
@@ -150,6 +156,12 @@ async function openCatalogEditModal(item) {
     document.getElementById('input-name').value = item.name;
     document.getElementById('input-kana').value = item.kana || '';
     document.getElementById('input-unit').value = item.default_unit;
+    document.getElementById('input-shelf-fridge').value = item.shelf_life_fridge || '';
+    document.getElementById('input-shelf-freezer').value = item.shelf_life_freezer || '';
+    document.getElementById('input-shelf-room').value = item.shelf_life_room || '';
+    document.getElementById('input-piece-unit').value = item.piece_unit || '';
+    document.getElementById('input-piece-grams').value = item.piece_grams || '';
+    document.getElementById('input-density').value = item.density || '';
 
     // カテゴリプルダウンをセット
     updateCategorySelectEdit(item.category);
@@ -275,6 +287,13 @@ async function saveSingleItem() {
         name: name,
         kana: document.getElementById('input-kana').value,
         default_unit: document.getElementById('input-unit').value,
+        // 空欄は 0（未設定）として送る
+        shelf_life_fridge: parseInt(document.getElementById('input-shelf-fridge').value) || 0,
+        shelf_life_freezer: parseInt(document.getElementById('input-shelf-freezer').value) || 0,
+        shelf_life_room: parseInt(document.getElementById('input-shelf-room').value) || 0,
+        piece_unit: document.getElementById('input-piece-unit').value.trim(),
+        piece_grams: parseFloat(document.getElementById('input-piece-grams').value) || 0,
+        density: parseFloat(document.getElementById('input-density').value) || 0,
         force_merge: false
     };
 
@@ -1,7 +1,7 @@
 var catalogListForInv = [];
 var currentSelectorCategory = 'すべて';
-// ★「ドアポケット」を追加
-var FIXED_LOCATIONS_EDIT = ["冷蔵室", "チルド", "冷凍室", "野菜室", "ドアポケット", "その他"];
+// inventory_list.js の loadLocationNames() で /api/locations の内容に置き換わる
+var FIXED_LOCATIONS_EDIT = ["冷蔵庫", "冷凍庫", "野菜室", "常温", "その他"];
 
 function initInventoryEdit() {
     setupLocationSelects();
@@ -118,8 +118,16 @@ function setupEditEventListeners() {
             })
             .then(async res => {
                 if (!res.ok) throw new Error(await res.text());
+                const saved = await res.json();
                 overlay.classList.remove('active');
                 if(window.fetchInventory) window.fetchInventory();
+                // 賞味期限を自動で入れた場合は根拠を知らせる（違っていれば編集で直してもらう）
+                if (saved.expiration_rule) {
+                    const rule = saved.expiration_rule;
+                    const storage = {fridge: '冷蔵', freezer: '冷凍', room: '常温'}[rule.storage_type] || rule.storage_type;
+                    const basis = rule.source === 'catalog' ? 'この食材の設定' : `カテゴリ「${rule.category}」の目安`;
+                    alert(`賞味期限を ${saved.expiration_date.split('T')[0]} にしました（${basis}: ${storage}で${rule.days}日）。\n違う場合は編集してください。`);
+                }
             })
             .catch(err => alert('保存エラー: ' + err));
         };
@@ -153,9 +161,12 @@ function setupEditEventListeners() {
 
     if (btnDelete) {
         btnDelete.onclick = () => {
-            if(!confirm('削除しますか？')) return;
+            const reasonSelect = document.getElementById('inv-delete-reason');
+            const reason = reasonSelect ? reasonSelect.value : 'other';
+            const label = reasonSelect ? reasonSelect.options[reasonSelect.selectedIndex].text : '';
+            if(!confirm(`「${label}」として削除しますか？`)) return;
             const id = document.getElementById('inv-edit-id').value;
-            fetch(`/api/ingredients?id=${id}`, { method: 'DELETE' })
+            fetch(`/api/ingredients?id=${id}&reason=${reason}`, { method: 'DELETE' })
             .then(async res => {
                 if(!res.ok) throw new Error(await res.text());
                 editOverlay.classList.remove('active');
@@ -254,6 +265,13 @@ window.openInventoryEdit = function(item) {
     document.getElementById('inv-edit-unit').textContent = item.unit;
     document.getElementById('inv-edit-date').value = item.expiration_date.split('T')[0];
 
+    // 期限切れなら削除理由の初期値を「期限切れで廃棄」にする
+    const reasonSelect = document.getElementById('inv-delete-reason');
+    if (reasonSelect) {
+        const exp = item.expiration_date ? new Date(item.expiration_date) : null;
+        reasonSelect.value = (exp && exp < new Date()) ? 'expired' : 'consumed';
+    }
+
     setupLocationSelects();
     const locSelect = document.getElementById('inv-edit-location');
     let loc = item.location;
@@ -1,10 +1,10 @@
 var inventoryData = [];
-// ★ドアポケット追加
-var FIXED_LOCATIONS = ["冷蔵室", "チルド", "冷凍室", "野菜室", "ドアポケット", "その他"];
+// 場所は /api/locations から読み込む（読み込み前はサーバーの初期値と同じ並び）
+var FIXED_LOCATIONS = ["冷蔵庫", "冷凍庫", "野菜室", "常温", "その他"];
 
 function initInventory() {
     if (window.fetchFridgePhotos) window.fetchFridgePhotos();
-    fetchInventory();
+    loadLocationNames().then(fetchInventory);
     if (typeof initInventoryEdit === 'function') initInventoryEdit();
     if (typeof setupPhotoUI === 'function') setupPhotoUI();
 }
@@ -29,6 +29,19 @@ window.fetchInventory = function() {
         .catch(err => console.error(err));
 };
 
+// 場所一覧を取得して一覧・編集画面の場所リストを差し替える
+function loadLocationNames() {
+    return fetch('/api/locations')
+        .then(res => res.json())
+        .then(data => {
+            if (!Array.isArray(data) || data.length === 0) return;
+            FIXED_LOCATIONS = data.map(loc => loc.name);
+            if (typeof FIXED_LOCATIONS_EDIT !== 'undefined') FIXED_LOCATIONS_EDIT = FIXED_LOCATIONS;
+            if (typeof setupLocationSelects === 'function') setupLocationSelects();
+        })
+        .catch(err => console.error(err));
+}
+
 function renderInventory(items) {
     const listEl = document.getElementById('inventory-list');
     if (!listEl) return;
@@ -109,10 +109,20 @@ window.moveLocation = function(id, direction) {
     });
 };
 
-window.deleteLocation = function(id) {
-    if(!confirm('削除しますか？')) return; 
-    fetch(`/api/locations?id=${id}`, { method: 'DELETE' }) 
-    .then(() => {
+window.deleteLocation = function(id, reassignTo) {
+    if(!reassignTo && !confirm('削除しますか？')) return; 
+    const url = reassignTo ? `/api/locations?id=${id}&reassign_to=${reassignTo}` : `/api/locations?id=${id}`;
+    fetch(url, { method: 'DELETE' }) 
+    .then(async res => {
+        if (res.status === 409) {
+            // 使用中: 「その他」(無ければ先頭) へ移してから削除するか確認
+            const data = await res.json();
+            const dest = locations.find(l => l.id !== id && l.name === 'その他') || locations.find(l => l.id !== id);
+            if (dest && confirm(`${data.message}\n「${dest.name}」へ移動して削除しますか？`)) {
+                return window.deleteLocation(id, dest.id);
+            }
+            return;
+        }
         window.fetchLocations().then(renderLocationManageList); 
     });
 };
\ No newline at end of file
@@ -47,8 +47,10 @@ function setupRecipeUI() {
 
     const btnCancel = document.getElementById('btn-rec-cancel');
     const btnSave = document.getElementById('btn-rec-save');
+    const btnImportURL = document.getElementById('btn-rec-import-url');
     const btnDetailClose = document.getElementById('btn-detail-close');
     const btnDetailEdit = document.getElementById('btn-detail-edit');
+    const btnDetailDelete = document.getElementById('btn-detail-delete');
     
     const btnMissingCancel = document.getElementById('btn-missing-cancel');
     const btnMissingRegister = document.getElementById('btn-missing-register');
@@ -74,12 +76,68 @@ function setupRecipeUI() {
     if(btnCancel) btnCancel.addEventListener('click', () => overlay.classList.remove('active'));
     if(btnDetailClose) btnDetailClose.addEventListener('click', () => detailOverlay.classList.remove('active'));
     if(btnDetailEdit) btnDetailEdit.addEventListener('click', () => openRecipeEditModal());
+    if(btnDetailDelete) btnDetailDelete.addEventListener('click', () => deleteCurrentRecipe());
 
     if(btnSave) btnSave.addEventListener('click', () => saveRecipe());
+    if(btnImportURL) btnImportURL.addEventListener('click', () => importRecipeFromURL());
     if(btnMissingCancel) btnMissingCancel.addEventListener('click', () => missingOverlay.classList.remove('active'));
     if(btnMissingRegister) btnMissingRegister.addEventListener('click', () => registerMissingItemsAndRetry());
 }
 
+// ゴミ箱へ移す（/api/recipes/restore で戻せる）
+function deleteCurrentRecipe() {
+    if (!currentRecipeDetail) return;
+    if (!confirm(`「${currentRecipeDetail.name}」をゴミ箱に移しますか？`)) return;
+
+    fetch(`/api/recipes?id=${currentRecipeDetail.id}`, { method: 'DELETE' })
+        .then(res => res.json().then(data => ({ ok: res.ok, data })))
+        .then(({ ok, data }) => {
+            if (!ok) {
+                alert('削除できませんでした: ' + (data.error || ''));
+                return;
+            }
+            document.getElementById('modal-recipe-detail').classList.remove('active');
+            fetchRecipes();
+        })
+        .catch(err => alert('通信エラー: ' + err));
+}
+
+// ページの schema.org Recipe を読み込んでフォームに入れる（保存はいつもどおり「保存する」で行う）
+function importRecipeFromURL() {
+    const url = document.getElementById('rec-url').value.trim();
+    if (!url) return alert('URLを入力してください');
+
+    const btn = document.getElementById('btn-rec-import-url');
+    btn.disabled = true;
+    fetch('/api/recipes/import_url', {
+        method: 'POST',
+        headers: {'Content-Type': 'application/json'},
+        body: JSON.stringify({ url: url })
+    })
+    .then(res => res.json().then(data => ({ ok: res.ok, data })))
+    .then(({ ok, data }) => {
+        if (!ok) throw new Error(data.error || '読み込めませんでした');
+
+        document.getElementById('rec-name').value = data.name;
+        document.getElementById('rec-yield').value = data.yield;
+        document.getElementById('rec-url').value = data.url;
+        document.getElementById('rec-process').value = data.process;
+        document.getElementById('rec-original-process').value = data.original_process;
+        document.getElementById('rec-csv').value = data.csv_data;
+        document.getElementById('rec-original-ingredients').value = data.original_ingredients;
+
+        const notes = [];
+        if (data.existing_id) notes.push(`同じURLか同じ名前のレシピが登録済みです（id=${data.existing_id}）`);
+        if (data.missing.length > 0) notes.push(`カタログに無い材料: ${data.missing.join('、')}\n（保存するときに別名・新規登録を選べます）`);
+        if (data.line_errors.length > 0) {
+            notes.push('読めなかった材料:\n' + data.line_errors.map(e => `${e.line}行目: ${e.message}（${e.text}）`).join('\n'));
+        }
+        if (notes.length > 0) alert(notes.join('\n\n'));
+    })
+    .catch(err => alert(err.message))
+    .finally(() => { btn.disabled = false; });
+}
+
 function saveRecipe() {
     const id = document.getElementById('rec-id').value;
     const name = document.getElementById('rec-name').value;
@@ -121,15 +179,20 @@ function saveRecipe() {
         const data = await res.json();
         
         if (!res.ok && data.error_code === 'missing_ingredients') {
-            showMissingIngredientsModal(data.items);
+            showMissingIngredientsModal(data.items, data.suggestions);
             throw new Error('missing_ingredients');
         }
 
         if (!res.ok) throw new Error(data.error || '登録エラー');
         return data;
     })
-    .then(() => {
-        alert(id ? 'レシピを更新しました！' : 'レシピを登録しました！');
+    .then(data => {
+        let msg = id ? 'レシピを更新しました！' : 'レシピを登録しました！';
+        if (data.line_errors && data.line_errors.length > 0) {
+            msg += '\n\n次の行は読めなかったため登録していません:\n' +
+                data.line_errors.map(e => `${e.line}行目: ${e.message}（${e.text}）`).join('\n');
+        }
+        alert(msg);
         document.getElementById('modal-recipe').classList.remove('active');
         if (typeof fetchRecipes === 'function') fetchRecipes();
     })
@@ -140,7 +203,7 @@ function saveRecipe() {
     });
 }
 
-function showMissingIngredientsModal(items) {
+function showMissingIngredientsModal(items, suggestions) {
     const overlay = document.getElementById('modal-missing-ing');
     const listArea = document.getElementById('missing-list-area');
     listArea.innerHTML = '';
@@ -150,12 +213,18 @@ function showMissingIngredientsModal(items) {
         div.style.background = '#f9f9f9';
         div.style.padding = '10px';
         div.style.borderRadius = '8px';
+        // 近いカタログ項目があれば先頭に出し、選ぶとその項目の別名として登録する（次からはそのまま通る）
+        const near = (suggestions && suggestions[name]) || [];
+        const nearOptions = near.map(c =>
+            `<option value="alias:${c.id}">もしかして「${c.name}」（別名にする）</option>`
+        ).join('');
         div.innerHTML = `
             <div style="font-weight:bold; margin-bottom:5px;">${name}</div>
             <div style="display:flex; gap:10px;">
                 <select id="missing-class-${index}" class="input-field" style="padding:8px; font-size:12px;">
-                    <option value="食材">食材</option>
-                    <option value="調味料">調味料</option>
+                    ${nearOptions}
+                    <option value="食材">新規：食材</option>
+                    <option value="調味料">新規：調味料</option>
                 </select>
                 <input type="hidden" id="missing-name-${index}" value="${name}">
             </div>
@@ -168,10 +237,15 @@ function showMissingIngredientsModal(items) {
 function registerMissingItemsAndRetry() {
     const listArea = document.getElementById('missing-list-area');
     const itemsToRegister = [];
+    const aliasesToRegister = [];
     const divs = listArea.querySelectorAll('.form-group');
     divs.forEach((div, index) => {
         const name = document.getElementById(`missing-name-${index}`).value;
         const cls = document.getElementById(`missing-class-${index}`).value;
+        if (cls.startsWith('alias:')) {
+            aliasesToRegister.push({ alias: name, catalog_id: parseInt(cls.slice(6), 10) });
+            return;
+        }
         itemsToRegister.push({
             name: name,
             classification: cls,
@@ -180,15 +254,26 @@ function registerMissingItemsAndRetry() {
         });
     });
 
-    fetch('/api/catalog', {
-        method: 'POST',
-        headers: {'Content-Type': 'application/json'},
-        body: JSON.stringify(itemsToRegister)
-    })
-    .then(res => {
-        if (!res.ok) throw new Error('カタログ登録に失敗しました');
-        return res.json();
-    })
+    const requests = aliasesToRegister.map(a =>
+        fetch('/api/catalog/aliases', {
+            method: 'POST',
+            headers: {'Content-Type': 'application/json'},
+            body: JSON.stringify(a)
+        }).then(async res => {
+            if (!res.ok) throw new Error((await res.json()).error || '別名の登録に失敗しました');
+        })
+    );
+    if (itemsToRegister.length > 0) {
+        requests.push(fetch('/api/catalog', {
+            method: 'POST',
+            headers: {'Content-Type': 'application/json'},
+            body: JSON.stringify(itemsToRegister)
+        }).then(res => {
+            if (!res.ok) throw new Error('カタログ登録に失敗しました');
+        }));
+    }
+
+    Promise.all(requests)
     .then(() => {
         document.getElementById('modal-missing-ing').classList.remove('active');
         saveRecipe(); 
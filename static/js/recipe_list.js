@@ -2,6 +2,7 @@ var recipeData = [];
 var currentRecipeDetail = null;
 var currentIngredients = [];
 var currentMissingItems = []; // ★追加: 一括追加用に不足リストを保持
+var recipeSearchTimer = null;
 
 function initRecipes() {
     const filterId = sessionStorage.getItem('recipe_filter_id');
@@ -10,15 +11,14 @@ function initRecipes() {
     const searchInput = document.getElementById('recipe-search');
     if (searchInput) {
         searchInput.addEventListener('input', (e) => {
-            const term = e.target.value.toLowerCase().trim();
+            const term = e.target.value.trim();
+            clearTimeout(recipeSearchTimer);
             if (!term) {
                 renderRecipes(recipeData);
                 return;
             }
-            const filtered = recipeData.filter(item => 
-                item.name.toLowerCase().includes(term)
-            );
-            renderRecipes(filtered);
+            // 入力が止まってからサーバーで検索する（かな・カナ・漢字の表記ゆれはサーバー側で吸収）
+            recipeSearchTimer = setTimeout(() => searchRecipes(term), 250);
         });
     }
     
@@ -55,6 +55,31 @@ function fetchFilteredRecipes(catalogId, itemName) {
         .catch(err => console.error(err));
 }
 
+function searchRecipes(term) {
+    fetch(`/api/recipes/search?limit=0&q=${encodeURIComponent(term)}`)
+        .then(res => res.json())
+        .then(results => {
+            const searchInput = document.getElementById('recipe-search');
+            if (searchInput && searchInput.value.trim() !== term) return; // 古い検索結果は捨てる
+            if (!Array.isArray(results)) {
+                renderRecipes([]);
+                return;
+            }
+            // 一覧に読み込み済みのレシピだけを関連度順に並べる（材料で絞り込み中ならその中から）
+            const byId = new Map(recipeData.map(item => [item.id, item]));
+            const matches = {};
+            const items = [];
+            results.forEach(r => {
+                const item = byId.get(r.id);
+                if (!item) return;
+                matches[r.id] = r;
+                items.push(item);
+            });
+            renderRecipes(items, matches);
+        })
+        .catch(err => console.error(err));
+}
+
 function showFilterHeader(itemName) {
     const listEl = document.getElementById('recipe-list');
     const existing = document.getElementById('filter-status-bar');
@@ -81,7 +106,7 @@ function showFilterHeader(itemName) {
     });
 }
 
-function renderRecipes(items) {
+function renderRecipes(items, matches) {
     const listEl = document.getElementById('recipe-list');
     if (!listEl) return;
     listEl.innerHTML = '';
@@ -97,14 +122,19 @@ function renderRecipes(items) {
         
         const ingIcon = item.has_ingredients ? '<span class="icon-strong">🥦</span>' : '<span class="icon-faint">🥦</span>';
         const seasIcon = item.has_seasonings ? '<span class="icon-strong">🧂</span>' : '<span class="icon-faint">🧂</span>';
+        // 検索結果なら一致箇所を強調した名前と抜粋を出す（サーバー側で HTML エスケープ済み）
+        const match = matches ? matches[item.id] : null;
+        const nameHtml = match ? match.name_highlight : item.name;
+        const snippetHtml = match && match.snippet ? `<div style="font-size:11px; color:#888;">${match.snippet}</div>` : '';
 
         div.innerHTML = `
             <div class="card-content">
                 <div style="display:flex; align-items:center; gap:8px;">
-                    <span class="item-name">${item.name}</span>
+                    <span class="item-name">${nameHtml}</span>
                     <span>${ingIcon} ${seasIcon}</span>
                 </div>
                 <div style="font-size:11px; color:#666;">${item.yield || ''}</div>
+                ${snippetHtml}
             </div>
             <div style="font-size:20px; color:#ccc;">›</div>
         `;
@@ -134,6 +164,11 @@ function openRecipeDetail(recipe) {
         link.style.display = 'none';
     }
 
+    ['html', 'markdown', 'jsonld'].forEach(format => {
+        const a = document.getElementById(`detail-export-${format}`);
+        if (a) a.href = `/api/recipes/export?id=${recipe.id}&format=${format}`;
+    });
+
     ingArea.innerHTML = '<div style="text-align:center; color:#999;">読み込み中...</div>';
     if (missingAlert) missingAlert.style.display = 'none';
 
@@ -153,8 +188,18 @@ function openRecipeDetail(recipe) {
             let currentGroup = "";
 
             ingredients.forEach(ing => {
-                const statusIcon = ing.in_stock ? '✅' : '❌';
+                let statusIcon = ing.in_stock ? '✅' : '❌';
                 const statusClass = ing.in_stock ? 'ing-status-ok' : 'ing-status-missing';
+
+                // 在庫はあるが量が足りない / 量を比べられない
+                const avail = ing.availability || {};
+                let shortHtml = '';
+                if (avail.status === 'short') {
+                    statusIcon = '⚠️';
+                    shortHtml = `<span style="font-size:11px; color:#e67e22; margin-left:4px;">あと${avail.shortfall}${avail.unit || ''}不足</span>`;
+                } else if (avail.status === 'unknown') {
+                    statusIcon = '❓';
+                }
                 
                 let addBtnHtml = '';
                 if (!ing.in_stock) {
@@ -182,6 +227,7 @@ function openRecipeDetail(recipe) {
                         <span class="${statusClass}">
                              ${statusIcon} ${ing.name}${detailsHtml}
                         </span>
+                        ${shortHtml}
                         ${addBtnHtml}
                     </div>
                     <span style="font-weight:bold; font-size:13px;">${combinedAmount}</span>
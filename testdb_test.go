@@ -0,0 +1,42 @@
+package main
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"kimichan/schema"
+)
+
+// マイグレーションを当てた空の DB をパッケージの db に差し替える。テストが終わったら元に戻す
+func newTestDB(t *testing.T) {
+	t.Helper()
+	testDB, err := sql.Open("sqlite3", schema.DSN(filepath.Join(t.TempDir(), "test.db")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := schema.Migrate(testDB); err != nil {
+		testDB.Close()
+		t.Fatal(err)
+	}
+	orig := db
+	db = testDB
+	t.Cleanup(func() {
+		db = orig
+		testDB.Close()
+	})
+}
+
+// テスト用の行を入れて、その id を返す
+func mustInsert(t *testing.T, query string, args ...interface{}) int {
+	t.Helper()
+	res, err := db.Exec(query, args...)
+	if err != nil {
+		t.Fatalf("%s: %v", query, err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return int(id)
+}
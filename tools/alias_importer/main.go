@@ -0,0 +1,113 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"kimichan/tools/common"
+)
+
+// 使い方:
+//
+//	go run ./tools/alias_importer                     # tools/manual_importer/substitutions.csv を取り込む
+//	go run ./tools/alias_importer -file path/to.csv   # CSVファイルを指定
+//
+// CSV は「source,target,details」（1行目はヘッダー）。source が別名、target がカタログ名。
+// 登録済みの別名・カタログに無い target・カタログ名と同じ source は飛ばすので、何度実行しても同じ結果になる
+func main() {
+	csvFlag := flag.String("file", "", "取り込むCSV (省略時は tools/manual_importer/substitutions.csv を探す)")
+	flag.Parse()
+
+	csvPath := *csvFlag
+	if csvPath == "" {
+		wd, _ := os.Getwd()
+		csvPath = filepath.Join(wd, "tools", "manual_importer", "substitutions.csv")
+		// ルート以外から実行された場合用
+		if _, err := os.Stat(csvPath); os.IsNotExist(err) {
+			csvPath = filepath.Join(wd, "..", "manual_importer", "substitutions.csv")
+		}
+	}
+
+	file, err := os.Open(csvPath)
+	if err != nil {
+		log.Fatalf("❌ CSVファイルが見つかりません: %s", csvPath)
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	// ヘッダーをスキップ
+	_, _ = reader.Read()
+	records, err := reader.ReadAll()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	db, err := common.ConnectDB()
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	fmt.Printf("📚 別名取込ツール: %s (%d 行)\n", csvPath, len(records))
+
+	tx, err := db.Begin()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	inserted, skipped := 0, 0
+	for _, record := range records {
+		if len(record) < 2 {
+			continue
+		}
+		alias := strings.TrimSpace(record[0])
+		target := strings.TrimSpace(record[1])
+		details := ""
+		if len(record) > 2 {
+			details = strings.TrimSpace(record[2])
+		}
+		if alias == "" || target == "" || alias == target {
+			continue
+		}
+
+		var id int
+		if err := tx.QueryRow("SELECT id FROM item_catalog WHERE name = ?", alias).Scan(&id); err == nil {
+			fmt.Printf("  ⏭️ %s: カタログに同じ名前があるためスキップ\n", alias)
+			skipped++
+			continue
+		}
+		if err := tx.QueryRow("SELECT id FROM item_aliases WHERE alias = ?", alias).Scan(&id); err == nil {
+			skipped++
+			continue
+		}
+		var catalogID int
+		err := tx.QueryRow("SELECT id FROM item_catalog WHERE name = ?", target).Scan(&catalogID)
+		if err == sql.ErrNoRows {
+			fmt.Printf("  ⚠️ %s → %s: カタログに「%s」が無いためスキップ\n", alias, target, target)
+			skipped++
+			continue
+		} else if err != nil {
+			tx.Rollback()
+			log.Fatal(err)
+		}
+
+		if _, err := tx.Exec("INSERT INTO item_aliases (alias, catalog_id, details) VALUES (?, ?, ?)", alias, catalogID, details); err != nil {
+			tx.Rollback()
+			log.Fatal("登録エラー:", err)
+		}
+		fmt.Printf("  ✅ %s → %s %s\n", alias, target, details)
+		inserted++
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("✨ 完了しました！ (登録: %d 件 / スキップ: %d 件)\n", inserted, skipped)
+}
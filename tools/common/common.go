@@ -12,6 +12,8 @@ import (
 	"strings"
 	"time"
 
+	"kimichan/schema"
+
 	_ "github.com/mattn/go-sqlite3"
 )
 
@@ -70,8 +72,21 @@ func LoadConfig() (*Config, error) {
 	return nil, fmt.Errorf("config.json が見つかりません")
 }
 
-// DBに接続する
+// DBに接続し、未適用のマイグレーションを適用する
 func ConnectDB() (*sql.DB, error) {
+	db, err := sql.Open("sqlite3", schema.DSN(DBPath()))
+	if err != nil {
+		return nil, err
+	}
+	if _, err := schema.Migrate(db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("スキーマ更新に失敗しました: %w", err)
+	}
+	return db, nil
+}
+
+// DBファイルの場所を探す
+func DBPath() string {
 	wd, _ := os.Getwd()
 
 	// ★変更: dataフォルダの中を優先的に探すように変更
@@ -98,7 +113,7 @@ func ConnectDB() (*sql.DB, error) {
 		os.MkdirAll("data", 0755) // 念のため作成
 	}
 
-	return sql.Open("sqlite3", dbPath)
+	return dbPath
 }
 
 // Geminiを呼び出す
@@ -23,6 +23,9 @@ func main() {
 		WHERE id NOT IN (SELECT DISTINCT catalog_id FROM recipe_ingredients) 
 		  AND id NOT IN (SELECT DISTINCT catalog_id FROM refrigerator_ingredients)
 		  AND id NOT IN (SELECT DISTINCT catalog_id FROM refrigerator_seasonings)
+		  AND id NOT IN (SELECT DISTINCT catalog_id FROM shopping_list)
+		  AND id NOT IN (SELECT DISTINCT catalog_id FROM ingredient_events)
+		  AND id NOT IN (SELECT DISTINCT catalog_id FROM item_aliases)
 	`
 
 	res, err := db.Exec(query)
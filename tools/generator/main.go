@@ -10,6 +10,8 @@ import (
 	"time"
 	"unicode/utf8"
 
+	"kimichan/catalog"
+	"kimichan/quantity"
 	"kimichan/tools/common"
 
 	"github.com/PuerkitoBio/goquery"
@@ -245,8 +247,13 @@ func saveRecipe(db *sql.DB, r *GeneratedRecipe, sourceURL string) error {
 			continue
 		}
 
+		// 名前・よみがな・別名で探し、無ければ新規登録する
 		var catalogID int
-		db.QueryRow("SELECT id FROM item_catalog WHERE name = ?", ing.Name).Scan(&catalogID)
+		details := ing.Details
+		if m, err := catalog.Resolve(tx, ing.Name); err == nil {
+			catalogID = m.ID
+			details = m.JoinDetails(details)
+		}
 
 		if catalogID == 0 {
 			res, err := tx.Exec("INSERT INTO item_catalog(name, classification, category, default_unit) VALUES(?, ?, ?, ?)",
@@ -259,8 +266,11 @@ func saveRecipe(db *sql.DB, r *GeneratedRecipe, sourceURL string) error {
 		}
 
 		// ★修正: unitは空文字、amountに単位込みの分量、detailsを保存
-		tx.Exec("INSERT INTO recipe_ingredients(recipe_id, catalog_id, unit, amount, group_name, details) VALUES(?, ?, ?, ?, ?, ?)",
-			recipeID, catalogID, "", ing.Amount, "", ing.Details)
+		q, _ := quantity.Parse(ing.Amount)
+		tx.Exec(`INSERT INTO recipe_ingredients(recipe_id, catalog_id, unit, amount, group_name, details,
+			quantity_value, quantity_max, quantity_unit, quantity_qualifier) VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			recipeID, catalogID, "", ing.Amount, "", details,
+			q.ValueOrNil(), q.MaxOrNil(), q.Unit, q.Qualifier)
 	}
 
 	if err := tx.Commit(); err != nil {
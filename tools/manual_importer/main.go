@@ -2,7 +2,6 @@ package main
 
 import (
 	"database/sql"
-	"encoding/csv"
 	"encoding/json"
 	"fmt"
 	"log"
@@ -11,11 +10,12 @@ import (
 	"strings"
 	"text/tabwriter"
 
+	"kimichan/catalog"
+	"kimichan/quantity"
 	"kimichan/tools/common"
 )
 
 const INPUT_FILE = "manual_input.txt"
-const SUBST_FILE = "substitutions.csv"
 
 type GeneratedRecipe struct {
 	Name        string `json:"name"`
@@ -38,7 +38,7 @@ type NormalizeResult struct {
 	Details      string `json:"details"`
 }
 
-// 強制変換ルール
+// 強制変換ルール（item_aliases の別名 → カタログ名）
 type Substitution struct {
 	TargetName string
 	Details    string
@@ -55,9 +55,6 @@ func main() {
 	}
 	apiKey = cfg.GeminiApiKey
 
-	// 辞書読み込み
-	loadSubstitutions()
-
 	// DB接続
 	db, err := common.ConnectDB()
 	if err != nil {
@@ -65,10 +62,10 @@ func main() {
 	}
 	defer db.Close()
 
-	fmt.Println("📝 手動レシピ取込ロボット (3列・辞書・ヨミガナ自動付与版)、起動...")
+	// 辞書読み込み
+	loadSubstitutions(db)
 
-	// テーブルがなければ作成する
-	fixDatabaseSchema(db)
+	fmt.Println("📝 手動レシピ取込ロボット (3列・辞書・ヨミガナ自動付与版)、起動...")
 
 	wd, _ := os.Getwd()
 	inputPath := filepath.Join(wd, INPUT_FILE)
@@ -109,60 +106,28 @@ func main() {
 	os.WriteFile(inputPath, []byte(""), 0644)
 }
 
-func loadSubstitutions() {
+// 別名の一覧（/api/catalog/aliases で管理）を読み込む。
+// 以前の substitutions.csv は tools/alias_importer で item_aliases に取り込める
+func loadSubstitutions(db *sql.DB) {
 	nameSubstitutions = make(map[string]Substitution)
 
-	// ★修正: 探索パスのパターンを増やしました
-	// 1. カレントディレクトリ (tools/manual_importerで実行時)
-	// 2. tools/manual_importer/ (プロジェクトルートで実行時)
-	// 3. ../../ (念のため)
-	candidates := []string{
-		SUBST_FILE,
-		filepath.Join("tools", "manual_importer", SUBST_FILE),
-		filepath.Join("..", "..", SUBST_FILE),
-	}
-
-	var file *os.File
-	var err error
-	var foundPath string
-
-	for _, path := range candidates {
-		file, err = os.Open(path)
-		if err == nil {
-			foundPath = path
-			break
-		}
-	}
-
-	if file == nil {
-		fmt.Println("⚠️ 誤変換辞書(CSV)が見つかりません。辞書なしで続行します。")
-		// デバッグ: どこを探したか表示した方が親切な場合はコメントアウトを外してください
-		// fmt.Printf("(探索パス: %v)\n", candidates)
+	rows, err := db.Query(`SELECT a.alias, c.name, COALESCE(a.details, '')
+		FROM item_aliases a JOIN item_catalog c ON a.catalog_id = c.id`)
+	if err != nil {
+		fmt.Println("⚠️ 別名を読み込めません。辞書なしで続行します:", err)
 		return
 	}
-	defer file.Close()
-
-	reader := csv.NewReader(file)
-	_, _ = reader.Read() // ヘッダースキップ
-	records, _ := reader.ReadAll()
+	defer rows.Close()
 
-	for _, record := range records {
-		if len(record) < 2 {
+	for rows.Next() {
+		var alias string
+		var sub Substitution
+		if err := rows.Scan(&alias, &sub.TargetName, &sub.Details); err != nil {
 			continue
 		}
-		nameSubstitutions[record[0]] = Substitution{
-			TargetName: record[1],
-			Details:    strings.TrimSpace(getAt(record, 2)),
-		}
-	}
-	fmt.Printf("📚 誤変換辞書を読み込みました(%s): %d件\n", foundPath, len(nameSubstitutions))
-}
-
-func getAt(s []string, i int) string {
-	if i < len(s) {
-		return s[i]
+		nameSubstitutions[alias] = sub
 	}
-	return ""
+	fmt.Printf("📚 別名辞書を読み込みました: %d件\n", len(nameSubstitutions))
 }
 
 // レシピデータに対して強制変換を適用する関数
@@ -240,9 +205,11 @@ func saveRecipe(db *sql.DB, r *GeneratedRecipe, sourceURL string) {
 			continue
 		}
 
-		// DB検索
+		// DB検索（名前・よみがな・別名）
 		var catalogID int
-		db.QueryRow("SELECT id FROM item_catalog WHERE name = ?", ing.Name).Scan(&catalogID)
+		if m, err := catalog.Resolve(tx, ing.Name); err == nil {
+			catalogID = m.ID
+		}
 
 		detailsToSave := ing.Details
 
@@ -313,8 +280,11 @@ func saveRecipe(db *sql.DB, r *GeneratedRecipe, sourceURL string) {
 		}
 
 		if catalogID != 0 {
-			_, err := tx.Exec("INSERT INTO recipe_ingredients(recipe_id, catalog_id, unit, amount, group_name, details) VALUES(?, ?, ?, ?, ?, ?)",
-				recipeID, catalogID, "", ing.Amount, ing.Group, detailsToSave)
+			q, _ := quantity.Parse(ing.Amount)
+			_, err := tx.Exec(`INSERT INTO recipe_ingredients(recipe_id, catalog_id, unit, amount, group_name, details,
+				quantity_value, quantity_max, quantity_unit, quantity_qualifier) VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+				recipeID, catalogID, "", ing.Amount, ing.Group, detailsToSave,
+				q.ValueOrNil(), q.MaxOrNil(), q.Unit, q.Qualifier)
 			if err != nil {
 				log.Printf("    ❌ 材料保存エラー(%s): %v", ing.Name, err)
 			}
@@ -355,50 +325,6 @@ JSON形式:
 	return &res, nil
 }
 
-func fixDatabaseSchema(db *sql.DB) {
-	db.Exec(`CREATE TABLE IF NOT EXISTS item_catalog (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		name TEXT NOT NULL UNIQUE,
-		kana TEXT,
-		classification TEXT NOT NULL,
-		category TEXT,
-		default_unit TEXT
-	);`)
-
-	db.Exec(`CREATE TABLE IF NOT EXISTS recipes (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		name TEXT NOT NULL UNIQUE,
-		yield TEXT,
-		process TEXT,
-		url TEXT,
-		original_ingredients TEXT DEFAULT '',
-		original_process TEXT DEFAULT '',
-		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
-	);`)
-
-	db.Exec(`CREATE TABLE IF NOT EXISTS recipe_ingredients (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		recipe_id INTEGER NOT NULL,
-		catalog_id INTEGER NOT NULL,
-		unit TEXT,
-		amount TEXT,
-		group_name TEXT,
-		details TEXT DEFAULT '',
-		FOREIGN KEY (recipe_id) REFERENCES recipes (id),
-		FOREIGN KEY (catalog_id) REFERENCES item_catalog (id)
-	);`)
-
-	sqls := []string{
-		"ALTER TABLE recipes ADD COLUMN original_ingredients TEXT DEFAULT ''",
-		"ALTER TABLE recipes ADD COLUMN original_process TEXT DEFAULT ''",
-		"ALTER TABLE recipe_ingredients ADD COLUMN details TEXT DEFAULT ''",
-		"ALTER TABLE item_catalog ADD COLUMN kana TEXT",
-	}
-	for _, q := range sqls {
-		db.Exec(q)
-	}
-}
-
 func analyzeManualText(text string, apiKey string) ([]GeneratedRecipe, string, error) {
 	prompt := `
 以下のテキストデータから、料理レシピの情報を抽出し、JSON配列で出力してください。
@@ -11,6 +11,7 @@ import (
 	"strings"
 	"time"
 
+	"kimichan/catalog"
 	"kimichan/tools/common"
 )
 
@@ -230,6 +231,31 @@ func executeMasterClean(db *sql.DB, oldID int, res *MasterCleanResult) error {
 	}
 
 	if masterID != oldID {
+		// 外部キーが有効なので、サーバーの統合と同じく参照している行をすべて統合先に付け替えてから消す
+		for _, table := range catalog.RefTables {
+			if table == "recipe_ingredients" {
+				continue // 上で詳細と一緒に付け替え済み
+			}
+			if _, err := tx.Exec("UPDATE "+table+" SET catalog_id = ? WHERE catalog_id = ?", masterID, oldID); err != nil {
+				tx.Rollback()
+				return fmt.Errorf("%s: %w", table, err)
+			}
+		}
+		// 調味料ストックは catalog_id ごとに1行なので、統合先に既にあれば統合元を捨てる
+		var masterHasSeasoning int
+		if err := tx.QueryRow("SELECT COUNT(*) FROM refrigerator_seasonings WHERE catalog_id = ?", masterID).Scan(&masterHasSeasoning); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if masterHasSeasoning > 0 {
+			_, err = tx.Exec("DELETE FROM refrigerator_seasonings WHERE catalog_id = ?", oldID)
+		} else {
+			_, err = tx.Exec("UPDATE refrigerator_seasonings SET catalog_id = ? WHERE catalog_id = ?", masterID, oldID)
+		}
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
 		_, err = tx.Exec("DELETE FROM item_catalog WHERE id = ?", oldID)
 		if err != nil {
 			tx.Rollback()
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"database/sql"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"kimichan/schema"
+	"kimichan/tools/common"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// 使い方:
+//
+//	go run ./tools/migrate status              # 現在のスキーマバージョンと未適用一覧
+//	go run ./tools/migrate up                  # 未適用のマイグレーションを適用
+//	go run ./tools/migrate -db path/to.db up   # DBファイルを指定
+func main() {
+	dbPath := flag.String("db", "", "対象のDBファイル (省略時は data/kimichan.db を探す)")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: migrate [-db path] status|up")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	path := *dbPath
+	if path == "" {
+		path = common.DBPath()
+	}
+	if _, err := os.Stat(path); err != nil {
+		log.Fatalf("❌ DBファイルが見つかりません: %s", path)
+	}
+
+	// ConnectDB は自動でマイグレーションしてしまうので、ここでは直接開く
+	db, err := sql.Open("sqlite3", schema.DSN(path))
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	switch flag.Arg(0) {
+	case "status":
+		printStatus(db, path)
+	case "up":
+		before, err := schema.CurrentVersion(db)
+		if err != nil {
+			log.Fatal(err)
+		}
+		applied, err := schema.Migrate(db)
+		if err != nil {
+			log.Fatalf("❌ %v", err)
+		}
+		if applied == 0 {
+			fmt.Printf("🆗 最新です (v%d)\n", before)
+			return
+		}
+		fmt.Printf("✨ v%d -> v%d (%d 件適用)\n", before, schema.Latest(), applied)
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+}
+
+func printStatus(db *sql.DB, path string) {
+	current, err := schema.CurrentVersion(db)
+	if err != nil {
+		log.Fatal(err)
+	}
+	list, err := schema.Status(db)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Printf("📂 %s\n", path)
+	fmt.Printf("📌 現在: v%d / 最新: v%d\n\n", current, schema.Latest())
+	pending := 0
+	for _, m := range list {
+		if m.Applied {
+			fmt.Printf("  ✅ %03d %-40s %s\n", m.Version, m.Name, m.AppliedAt)
+		} else {
+			fmt.Printf("  ⏳ %03d %s\n", m.Version, m.Name)
+			pending++
+		}
+	}
+	if pending > 0 {
+		fmt.Printf("\n%d 件が未適用です。`migrate up` で適用できます。\n", pending)
+	}
+
+	violations, err := schema.ForeignKeyViolations(db)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(violations) > 0 {
+		fmt.Println("\n⚠️ 外部キー制約に違反している行があります:")
+		for ref, n := range violations {
+			fmt.Printf("  %s: %d 件\n", ref, n)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+
+	"kimichan/quantity"
+	"kimichan/tools/common"
+)
+
+// recipe_ingredients.amount を quantity パッケージで分解して quantity_* カラムを埋める。
+// amount（元の文字列）は書き換えない。
+//
+//	go run ./tools/quantity_backfill            # 未処理の行だけ
+//	go run ./tools/quantity_backfill -all       # 全行を解析し直す（パーサー更新後など）
+//	go run ./tools/quantity_backfill -dry-run   # 書き込まずに結果だけ表示
+func main() {
+	all := flag.Bool("all", false, "埋まっている行も解析し直す")
+	dryRun := flag.Bool("dry-run", false, "DBに書き込まない")
+	flag.Parse()
+
+	db, err := common.ConnectDB()
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	fmt.Println("📏 分量の解析ロボット、起動します...")
+
+	query := "SELECT id, COALESCE(amount, '') FROM recipe_ingredients"
+	if !*all {
+		query += " WHERE quantity_value IS NULL AND COALESCE(quantity_qualifier, '') = ''"
+	}
+	rows, err := db.Query(query)
+	if err != nil {
+		log.Fatal(err)
+	}
+	type target struct {
+		id     int
+		amount string
+	}
+	var targets []target
+	for rows.Next() {
+		var t target
+		if err := rows.Scan(&t.id, &t.amount); err != nil {
+			log.Fatal(err)
+		}
+		targets = append(targets, t)
+	}
+	rows.Close()
+
+	tx, err := db.Begin()
+	if err != nil {
+		log.Fatal(err)
+	}
+	stmt, err := tx.Prepare("UPDATE recipe_ingredients SET quantity_value = ?, quantity_max = ?, quantity_unit = ?, quantity_qualifier = ? WHERE id = ?")
+	if err != nil {
+		tx.Rollback()
+		log.Fatal(err)
+	}
+	defer stmt.Close()
+
+	var withValue, qualifierOnly int
+	unparsed := map[string]int{}
+	for _, t := range targets {
+		q, err := quantity.Parse(t.amount)
+		switch {
+		case err != nil:
+			if t.amount != "" {
+				unparsed[t.amount]++
+			}
+		case q.HasValue():
+			withValue++
+		default:
+			qualifierOnly++
+		}
+		if _, err := stmt.Exec(q.ValueOrNil(), q.MaxOrNil(), q.Unit, q.Qualifier, t.id); err != nil {
+			tx.Rollback()
+			log.Fatalf("❌ 更新エラー (id=%d): %v", t.id, err)
+		}
+	}
+
+	if *dryRun {
+		tx.Rollback()
+		fmt.Println("（dry-run のため書き込みはしていません）")
+	} else if err := tx.Commit(); err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Printf("✅ 対象 %d 行: 数値あり %d / 少々・適量など %d / 読めない %d\n",
+		len(targets), withValue, qualifierOnly, len(targets)-withValue-qualifierOnly)
+	if len(unparsed) > 0 {
+		fmt.Println("⚠️ 数値を読み取れなかった分量:")
+		for amount, n := range unparsed {
+			fmt.Printf("   %s (%d件)\n", amount, n)
+		}
+	}
+}
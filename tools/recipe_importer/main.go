@@ -0,0 +1,191 @@
+package main
+
+import (
+	"database/sql"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"kimichan/catalog"
+	"kimichan/quantity"
+	"kimichan/recipetext"
+	"kimichan/recipeweb"
+	"kimichan/tools/common"
+)
+
+// 使い方:
+//
+//	go run ./tools/recipe_importer URL [URL ...]                  # ページの schema.org Recipe を読んで保存
+//	go run ./tools/recipe_importer -dry-run URL                   # 読み取った内容を表示するだけ
+//	go run ./tools/recipe_importer -create-missing 食材 URL        # カタログに無い材料を登録して保存
+//
+// 材料はサーバーの POST /api/recipes と同じく recipetext で列に分け、catalog で名前・よみがな・別名から探す。
+// カタログに無い材料があるレシピは、-create-missing を付けない限り候補を表示して保存しない。
+// 同じ URL か同じ名前のレシピが登録済みなら飛ばすので、何度実行しても同じ結果になる
+func main() {
+	dryRun := flag.Bool("dry-run", false, "保存せずに読み取った内容を表示する")
+	createMissing := flag.String("create-missing", "", "カタログに無い材料をこの分類（食材 / 調味料）で登録する")
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "使い方: recipe_importer [-dry-run] [-create-missing 食材|調味料] URL [URL ...]")
+		os.Exit(2)
+	}
+	if *createMissing != "" && *createMissing != "食材" && *createMissing != "調味料" {
+		log.Fatal("-create-missing は 食材 か 調味料 を指定してください")
+	}
+
+	db, err := common.ConnectDB()
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	saved, skipped := 0, 0
+	for i, pageURL := range flag.Args() {
+		if i > 0 {
+			time.Sleep(2 * time.Second)
+		}
+		fmt.Printf("\n🌐 取得中: %s\n", pageURL)
+
+		recipe, err := recipeweb.Fetch(client, pageURL)
+		if err != nil {
+			fmt.Printf("  ❌ %v\n", err)
+			skipped++
+			continue
+		}
+		ok, err := importRecipe(db, recipe, *createMissing, *dryRun)
+		if err != nil {
+			fmt.Printf("  ❌ 保存エラー: %v\n", err)
+			skipped++
+			continue
+		}
+		if ok {
+			saved++
+		} else {
+			skipped++
+		}
+	}
+	fmt.Printf("\n✨ 完了しました！ (保存: %d 件 / スキップ: %d 件)\n", saved, skipped)
+}
+
+// 1件分を解決して保存する。保存しなかったときは false
+func importRecipe(db *sql.DB, r *recipeweb.Recipe, createMissing string, dryRun bool) (bool, error) {
+	fmt.Printf("  🍳 %s (%s, %s)\n", r.Name, r.Yield, r.Source)
+	if r.Name == "" {
+		fmt.Println("  ⚠️ レシピ名が読み取れないためスキップ")
+		return false, nil
+	}
+
+	lines, lineErrors := recipetext.Parse(r.IngredientText())
+	for _, e := range lineErrors {
+		fmt.Printf("  ⚠️ 材料 %v\n", e)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	var existingID int
+	err = tx.QueryRow("SELECT id FROM recipes WHERE url = ? OR name = ? LIMIT 1", r.URL, r.Name).Scan(&existingID)
+	if err == nil {
+		fmt.Printf("  ⏭️ 登録済み (id=%d)\n", existingID)
+		return false, nil
+	} else if err != sql.ErrNoRows {
+		return false, err
+	}
+
+	matches := make([]*catalog.Match, len(lines))
+	var missing []string
+	for i, l := range lines {
+		m, err := catalog.Resolve(tx, l.Name)
+		if err == catalog.ErrNotFound && createMissing != "" && !dryRun {
+			// 同じ名前が2回出てきても、2回目は登録したものに当たる
+			m, err = createCatalogItem(tx, l.Name, createMissing)
+		}
+		if err == catalog.ErrNotFound {
+			missing = append(missing, l.Name)
+		} else if err != nil {
+			return false, err
+		}
+		matches[i] = m
+
+		status := "❓ カタログに無し"
+		if m != nil {
+			status = "→ " + m.Name
+		}
+		fmt.Printf("    [%s] %s %s %s %s\n", l.Group, l.Name, l.Amount, l.Details, status)
+	}
+
+	if len(missing) > 0 {
+		fmt.Println("  ⚠️ カタログに無い材料:")
+		for _, name := range missing {
+			candidates, err := catalog.Suggest(tx, name, 3)
+			if err != nil {
+				return false, err
+			}
+			var names []string
+			for _, c := range candidates {
+				names = append(names, c.Name)
+			}
+			if len(names) > 0 {
+				fmt.Printf("    - %s (もしかして: %s)\n", name, strings.Join(names, " / "))
+			} else {
+				fmt.Printf("    - %s\n", name)
+			}
+		}
+		if !dryRun {
+			fmt.Println("  ⏭️ 別名を登録するか -create-missing を付けて実行してください")
+			return false, nil
+		}
+	}
+	if dryRun {
+		return false, nil
+	}
+
+	res, err := tx.Exec("INSERT INTO recipes(name, yield, process, original_ingredients, original_process, url) VALUES(?, ?, ?, ?, ?, ?)",
+		r.Name, r.Yield, r.ProcessText(), r.IngredientText(), r.ProcessText(), r.URL)
+	if err != nil {
+		return false, err
+	}
+	recipeID, _ := res.LastInsertId()
+
+	for i, l := range lines {
+		q, _ := quantity.Parse(l.Amount)
+		if _, err := tx.Exec(`INSERT INTO recipe_ingredients(recipe_id, catalog_id, unit, amount, group_name, details,
+			quantity_value, quantity_max, quantity_unit, quantity_qualifier) VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			recipeID, matches[i].ID, "", l.Amount, l.Group, matches[i].JoinDetails(l.Details),
+			q.ValueOrNil(), q.MaxOrNil(), q.Unit, q.Qualifier); err != nil {
+			return false, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, err
+	}
+	fmt.Printf("  ✅ 保存完了 (id=%d)\n", recipeID)
+	return true, nil
+}
+
+// サーバーの create_missing と同じく、食材は単位「個」・カテゴリ「その他」、調味料は単位・カテゴリなしで登録する
+func createCatalogItem(tx *sql.Tx, name, classification string) (*catalog.Match, error) {
+	category, unit := "その他", "個"
+	if classification == "調味料" {
+		category, unit = "", ""
+	}
+	res, err := tx.Exec("INSERT INTO item_catalog(name, kana, classification, category, default_unit) VALUES(?, ?, ?, ?, ?)",
+		name, "", classification, category, unit)
+	if err != nil {
+		return nil, err
+	}
+	id, _ := res.LastInsertId()
+	fmt.Printf("    🆕 カタログに登録: %s (%s)\n", name, classification)
+	return &catalog.Match{ID: int(id), Name: name}, nil
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"kimichan/accounts"
+	"kimichan/tools/common"
+)
+
+// 使い方:
+//
+//	go run ./tools/user list
+//	go run ./tools/user add [-admin] <ユーザー名>   # パスワードは標準入力から
+//	go run ./tools/user reset <ユーザー名>          # パスワード再設定（ログイン中のセッションは無効化）
+//	go run ./tools/user delete <ユーザー名>
+//
+// 環境変数 KIMICHAN_PASSWORD があれば、入力を求めずにそれを使います。
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: user list | add [-admin] <name> | reset <name> | delete <name>")
+	}
+	flag.Parse()
+	if flag.NArg() < 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	db, err := common.ConnectDB()
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	cmd, args := flag.Arg(0), flag.Args()[1:]
+	switch cmd {
+	case "list":
+		users, err := accounts.ListUsers(db)
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, u := range users {
+			role := ""
+			if u.IsAdmin {
+				role = " (管理者)"
+			}
+			fmt.Printf("  %d: %s%s\n", u.ID, u.Username, role)
+		}
+		fmt.Printf("👤 %d 人\n", len(users))
+
+	case "add":
+		fs := flag.NewFlagSet("add", flag.ExitOnError)
+		admin := fs.Bool("admin", false, "管理者として作成")
+		fs.Parse(args)
+		name := requireName(fs.Args())
+		u, err := accounts.CreateUser(db, name, readPassword(), *admin)
+		if err != nil {
+			log.Fatalf("❌ %v", err)
+		}
+		fmt.Printf("✅ ユーザー「%s」を作成しました (id=%d)\n", u.Username, u.ID)
+
+	case "reset":
+		name := requireName(args)
+		if err := accounts.SetPassword(db, name, readPassword()); err != nil {
+			log.Fatalf("❌ %v", err)
+		}
+		fmt.Printf("✅ 「%s」のパスワードを再設定しました\n", name)
+
+	case "delete":
+		name := requireName(args)
+		if err := accounts.DeleteUser(db, name); err != nil {
+			log.Fatalf("❌ %v", err)
+		}
+		fmt.Printf("🗑️ 「%s」を削除しました\n", name)
+
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+}
+
+func requireName(args []string) string {
+	if len(args) != 1 || strings.TrimSpace(args[0]) == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+	return strings.TrimSpace(args[0])
+}
+
+func readPassword() string {
+	if p := os.Getenv("KIMICHAN_PASSWORD"); p != "" {
+		return p
+	}
+	fmt.Print("パスワード: ")
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		log.Fatal("パスワードが入力されませんでした")
+	}
+	return strings.TrimRight(line, "\r\n")
+}
@@ -0,0 +1,152 @@
+// Package units は料理の単位（大さじ・カップ・g・個 …）を相互に変換します。
+// 体積どうし・重さどうしは共通の換算、体積⇔重さは密度、個数⇔重さは1個あたりの重さを使います。
+package units
+
+import (
+	"errors"
+	"fmt"
+)
+
+// 単位の種類
+type Dimension int
+
+const (
+	Unknown Dimension = iota
+	Volume            // ml 基準
+	Weight            // g 基準
+	Piece             // 個・本・枚など（品目ごとに PieceUnit で決める）
+)
+
+var (
+	ErrUnknownUnit    = errors.New("units: 知らない単位です")
+	ErrNotConvertible = errors.New("units: 変換できません")
+)
+
+// 体積の単位（ml 換算）。quantity パッケージで正規化した表記に合わせる
+var volumeUnits = map[string]float64{
+	"ml":  1,
+	"L":   1000,
+	"大さじ": 15,
+	"小さじ": 5,
+	"カップ": 200,
+	"合":   180,
+}
+
+// 重さの単位（g 換算）
+var weightUnits = map[string]float64{
+	"mg": 0.001,
+	"g":  1,
+	"kg": 1000,
+}
+
+// カタログ項目ごとの換算係数（item_catalog の density / piece_unit / piece_grams）
+type Factors struct {
+	Density    float64 // 1ml あたりの g（0 なら未設定）
+	PieceUnit  string  // 個数として数える単位（個・本・玉 など）
+	PieceGrams float64 // PieceUnit 1つあたりの g
+}
+
+// 単位の種類と、基準単位（ml / g / PieceUnit 1つ）への倍率を返す
+func Lookup(unit string, f Factors) (Dimension, float64) {
+	if v, ok := volumeUnits[unit]; ok {
+		return Volume, v
+	}
+	if v, ok := weightUnits[unit]; ok {
+		return Weight, v
+	}
+	if unit != "" && unit == f.PieceUnit {
+		return Piece, 1
+	}
+	return Unknown, 0
+}
+
+// 変換の経路（どの係数を使ったか）
+type Result struct {
+	Value float64  `json:"value"`
+	Via   []string `json:"via"` // "volume" / "weight" / "density" / "piece_weight"
+}
+
+// value を from から to に変換する
+func Convert(value float64, from, to string, f Factors) (Result, error) {
+	if from == to {
+		return Result{Value: value, Via: []string{}}, nil
+	}
+	fromDim, fromScale := Lookup(from, f)
+	if fromDim == Unknown {
+		return Result{}, fmt.Errorf("%w: %s", ErrUnknownUnit, from)
+	}
+	toDim, toScale := Lookup(to, f)
+	if toDim == Unknown {
+		return Result{}, fmt.Errorf("%w: %s", ErrUnknownUnit, to)
+	}
+
+	base := value * fromScale
+	var via []string
+	if fromDim == toDim {
+		via = append(via, dimensionName(fromDim))
+		return Result{Value: base / toScale, Via: via}, nil
+	}
+
+	// いったん g に揃えてから目的の単位へ
+	grams, err := toGrams(base, fromDim, f, &via)
+	if err != nil {
+		return Result{}, fmt.Errorf("%w: %s → %s (%v)", ErrNotConvertible, from, to, err)
+	}
+	out, err := fromGrams(grams, toDim, f, &via)
+	if err != nil {
+		return Result{}, fmt.Errorf("%w: %s → %s (%v)", ErrNotConvertible, from, to, err)
+	}
+	return Result{Value: out / toScale, Via: via}, nil
+}
+
+func toGrams(base float64, dim Dimension, f Factors, via *[]string) (float64, error) {
+	switch dim {
+	case Weight:
+		return base, nil
+	case Volume:
+		if f.Density <= 0 {
+			return 0, errors.New("密度が未設定です")
+		}
+		*via = append(*via, "density")
+		return base * f.Density, nil
+	case Piece:
+		if f.PieceGrams <= 0 {
+			return 0, errors.New("1つあたりの重さが未設定です")
+		}
+		*via = append(*via, "piece_weight")
+		return base * f.PieceGrams, nil
+	}
+	return 0, ErrUnknownUnit
+}
+
+func fromGrams(grams float64, dim Dimension, f Factors, via *[]string) (float64, error) {
+	switch dim {
+	case Weight:
+		return grams, nil
+	case Volume:
+		if f.Density <= 0 {
+			return 0, errors.New("密度が未設定です")
+		}
+		*via = append(*via, "density")
+		return grams / f.Density, nil
+	case Piece:
+		if f.PieceGrams <= 0 {
+			return 0, errors.New("1つあたりの重さが未設定です")
+		}
+		*via = append(*via, "piece_weight")
+		return grams / f.PieceGrams, nil
+	}
+	return 0, ErrUnknownUnit
+}
+
+func dimensionName(d Dimension) string {
+	switch d {
+	case Volume:
+		return "volume"
+	case Weight:
+		return "weight"
+	case Piece:
+		return "piece"
+	}
+	return "unknown"
+}
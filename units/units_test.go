@@ -0,0 +1,76 @@
+package units
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestConvert(t *testing.T) {
+	soy := Factors{Density: 1.2}                      // 醤油
+	onion := Factors{PieceUnit: "個", PieceGrams: 200} // 玉ねぎ
+	butter := Factors{Density: 0.9, PieceUnit: "箱", PieceGrams: 200}
+
+	tests := []struct {
+		name     string
+		value    float64
+		from, to string
+		f        Factors
+		want     float64
+		via      []string
+		err      error
+	}{
+		{"同じ単位", 3, "個", "個", Factors{}, 3, []string{}, nil},
+		{"体積どうし", 2, "大さじ", "ml", Factors{}, 30, []string{"volume"}, nil},
+		{"カップから小さじ", 1, "カップ", "小さじ", Factors{}, 40, []string{"volume"}, nil},
+		{"重さどうし", 1.5, "kg", "g", Factors{}, 1500, []string{"weight"}, nil},
+		{"体積から重さ（密度）", 1, "大さじ", "g", soy, 18, []string{"density"}, nil},
+		{"重さから体積（密度）", 120, "g", "ml", soy, 100, []string{"density"}, nil},
+		{"個数から重さ", 2, "個", "g", onion, 400, []string{"piece_weight"}, nil},
+		{"重さから個数", 1, "kg", "個", onion, 5, []string{"piece_weight"}, nil},
+		{"個数から体積（1つの重さと密度）", 1, "箱", "カップ", butter, 200.0 / 0.9 / 200, []string{"piece_weight", "density"}, nil},
+
+		{"知らない単位", 1, "束", "g", Factors{}, 0, nil, ErrUnknownUnit},
+		{"PieceUnit と違う個数の単位", 1, "本", "g", onion, 0, nil, ErrUnknownUnit},
+		{"密度が未設定", 1, "大さじ", "g", Factors{}, 0, nil, ErrNotConvertible},
+		{"1つの重さが未設定", 1, "個", "g", Factors{PieceUnit: "個"}, 0, nil, ErrNotConvertible},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Convert(tt.value, tt.from, tt.to, tt.f)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Convert(%v, %q, %q) error = %v, want %v", tt.value, tt.from, tt.to, err, tt.err)
+			}
+			if err != nil {
+				return
+			}
+			if math.Abs(got.Value-tt.want) > 1e-9 {
+				t.Errorf("Convert(%v, %q, %q) = %v, want %v", tt.value, tt.from, tt.to, got.Value, tt.want)
+			}
+			if !reflect.DeepEqual(got.Via, tt.via) {
+				t.Errorf("Convert(%v, %q, %q).Via = %v, want %v", tt.value, tt.from, tt.to, got.Via, tt.via)
+			}
+		})
+	}
+}
+
+func TestLookup(t *testing.T) {
+	f := Factors{PieceUnit: "本"}
+	tests := []struct {
+		unit  string
+		dim   Dimension
+		scale float64
+	}{
+		{"合", Volume, 180},
+		{"mg", Weight, 0.001},
+		{"本", Piece, 1},
+		{"個", Unknown, 0},
+		{"", Unknown, 0},
+	}
+	for _, tt := range tests {
+		if dim, scale := Lookup(tt.unit, f); dim != tt.dim || scale != tt.scale {
+			t.Errorf("Lookup(%q) = %v, %v, want %v, %v", tt.unit, dim, scale, tt.dim, tt.scale)
+		}
+	}
+}